@@ -1,97 +1,174 @@
 package api
 
 import (
-	"fmt"
-	"io"
-	"net/http"
 	"sync"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/events"
+	"github.com/docker/swarm/metrics"
 )
 
-// EventsHandler broadcasts events to multiple client listeners.
+const (
+	// Number of events kept in memory for replay.
+	eventsHistorySize = 1000
+	// Number of events queued for a subscriber before it's considered too
+	// slow and dropped.
+	eventsQueueSize = 100
+)
+
+// eventRecord is an event along with the attributes it can be filtered on.
+type eventRecord struct {
+	Message events.Message
+	events.Attributes
+}
+
+// eventsSubscriber receives the events matching its filter through a
+// buffered queue, so that slow subscribers don't block the others.
+type eventsSubscriber struct {
+	filter events.Filter
+	queue  chan *eventRecord
+}
+
+// EventsHandler broadcasts events to multiple client listeners and keeps a
+// history of them for replay.
 type eventsHandler struct {
 	sync.RWMutex
-	ws map[string]io.Writer
-	cs map[string]chan struct{}
+	subscribers map[*eventsSubscriber]struct{}
+	history     *eventsHistory
+
+	virtualIDs bool
 }
 
 // NewEventsHandler creates a new EventsHandler for a cluster.
-// The new eventsHandler is initialized with no writers or channels.
+// The new eventsHandler is initialized with no subscribers and an empty
+// history.
 func newEventsHandler() *eventsHandler {
 	return &eventsHandler{
-		ws: make(map[string]io.Writer),
-		cs: make(map[string]chan struct{}),
+		subscribers: make(map[*eventsSubscriber]struct{}),
+		history:     newEventsHistory(eventsHistorySize),
+	}
+}
+
+// Subscribe registers a new subscriber for the events matching filter. It
+// returns the subscriber along with the events of the history since the
+// given time that match the filter, if since is set.
+func (eh *eventsHandler) Subscribe(filter events.Filter, since int64) (*eventsSubscriber, []*eventRecord) {
+	sub := &eventsSubscriber{
+		filter: filter,
+		queue:  make(chan *eventRecord, eventsQueueSize),
 	}
+
+	// Hold the lock while reading the history so that no event is missed
+	// or sent twice.
+	eh.Lock()
+	defer eh.Unlock()
+
+	var past []*eventRecord
+	if since > 0 {
+		for _, record := range eh.history.Events() {
+			if record.Message.Time >= since && filter.Match(record.Message, record.Attributes) {
+				past = append(past, record)
+			}
+		}
+	}
+	eh.subscribers[sub] = struct{}{}
+
+	return sub, past
 }
 
-// Add adds the writer and a new channel for the remote address.
-func (eh *eventsHandler) Add(remoteAddr string, w io.Writer) {
+// Unsubscribe removes a subscriber and closes its queue.
+func (eh *eventsHandler) Unsubscribe(sub *eventsSubscriber) {
 	eh.Lock()
-	eh.ws[remoteAddr] = w
-	eh.cs[remoteAddr] = make(chan struct{})
+	eh.unsubscribe(sub)
 	eh.Unlock()
 }
 
-// Wait waits on a signal from the remote address.
-func (eh *eventsHandler) Wait(remoteAddr string) {
-	<-eh.cs[remoteAddr]
+func (eh *eventsHandler) unsubscribe(sub *eventsSubscriber) {
+	if _, ok := eh.subscribers[sub]; ok {
+		delete(eh.subscribers, sub)
+		close(sub.queue)
+	}
 }
 
-// Handle writes information about a cluster event to each remote address in the cluster that has been added to the events handler.
-// After a successful write to a remote address, the associated channel is closed and the address is removed from the events handler.
+// Handle records a cluster event in the history and queues it for every
+// subscriber whose filter it matches. Subscribers whose queue is full are
+// dropped.
 func (eh *eventsHandler) Handle(e *cluster.Event) error {
+	record := eh.newRecord(e)
+
 	eh.RLock()
 
-	str := fmt.Sprintf("{%q:%q,%q:%q,%q:%q,%q:%d,%q:{%q:%q,%q:%q,%q:%q,%q:%q}}",
-		"status", e.Status,
-		"id", e.Id,
-		"from", e.From+" node:"+e.Engine.Name,
-		"time", e.Time,
-		"node",
-		"Name", e.Engine.Name,
-		"Id", e.Engine.ID,
-		"Addr", e.Engine.Addr,
-		"Ip", e.Engine.IP)
-
-	var failed []string
-
-	for key, w := range eh.ws {
-		if _, err := fmt.Fprintf(w, str); err != nil {
-			// collect them to handle later under Lock
-			failed = append(failed, key)
+	eh.history.Add(record)
+
+	var slow []*eventsSubscriber
+	for sub := range eh.subscribers {
+		if !sub.filter.Match(record.Message, record.Attributes) {
 			continue
 		}
-
-		if f, ok := w.(http.Flusher); ok {
-			f.Flush()
+		select {
+		case sub.queue <- record:
+		default:
+			// collect them to handle later under Lock
+			slow = append(slow, sub)
 		}
-
 	}
 
 	eh.RUnlock()
 
-	if len(failed) > 0 {
+	if len(slow) > 0 {
 		eh.Lock()
-
-		for _, key := range failed {
-			if ch, ok := eh.cs[key]; ok {
-				close(ch)
-				// the maps are expected to have the same keys
-				delete(eh.cs, key)
-				delete(eh.ws, key)
-			}
+		for _, sub := range slow {
+			log.Warn("Dropping slow events subscriber")
+			eh.unsubscribe(sub)
 		}
-
 		eh.Unlock()
 	}
 
 	return nil
 }
 
-// Size returns the number of remote addresses that the events handler currently contains.
+func (eh *eventsHandler) newRecord(e *cluster.Event) *eventRecord {
+	record := &eventRecord{
+		Message:    events.NewMessage(e),
+		Attributes: events.NewAttributes(e),
+	}
+	if eh.virtualIDs && e.Container != nil {
+		record.Message.ID = containerID(true, e.Container)
+	}
+	return record
+}
+
+// Collect returns the metrics of the events handler.
+func (eh *eventsHandler) Collect() []*metrics.Family {
+	eh.RLock()
+	defer eh.RUnlock()
+
+	depth := 0
+	for sub := range eh.subscribers {
+		depth += len(sub.queue)
+	}
+
+	return []*metrics.Family{
+		{
+			Name:    "swarm_events_subscribers",
+			Help:    "Number of clients listening to the events API.",
+			Type:    metrics.TypeGauge,
+			Samples: []metrics.Sample{{Value: float64(len(eh.subscribers))}},
+		},
+		{
+			Name:    "swarm_events_queue_depth",
+			Help:    "Number of events waiting to be delivered, by consumer.",
+			Type:    metrics.TypeGauge,
+			Samples: []metrics.Sample{{Labels: []metrics.Label{{Name: "consumer", Value: "api"}}, Value: float64(depth)}},
+		},
+	}
+}
+
+// Size returns the number of subscribers the events handler currently
+// contains.
 func (eh *eventsHandler) Size() int {
 	eh.RLock()
 	defer eh.RUnlock()
-	return len(eh.ws)
+	return len(eh.subscribers)
 }
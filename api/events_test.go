@@ -1,31 +1,20 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/events"
+	"github.com/samalba/dockerclient"
 	"github.com/stretchr/testify/assert"
 )
 
-type FakeWriter struct {
-	Tmp []byte
-}
-
-func (fw *FakeWriter) Write(p []byte) (n int, err error) {
-	fw.Tmp = append(fw.Tmp, p...)
-	return len(p), nil
-}
-
-func TestHandle(t *testing.T) {
-	eh := newEventsHandler()
-	assert.Equal(t, eh.Size(), 0)
-
-	fw := &FakeWriter{Tmp: []byte{}}
-	eh.Add("test", fw)
-
-	assert.Equal(t, eh.Size(), 1)
-
+func newTestEvent(status, id, from string, time int64) *cluster.Event {
 	event := &cluster.Event{
 		Engine: &cluster.Engine{
 			ID:   "node_id",
@@ -35,12 +24,27 @@ func TestHandle(t *testing.T) {
 		},
 	}
 
-	event.Event.Status = "status"
-	event.Event.Id = "id"
-	event.Event.From = "from"
-	event.Event.Time = 0
+	event.Event.Status = status
+	event.Event.Id = id
+	event.Event.From = from
+	event.Event.Time = time
+	return event
+}
 
-	assert.NoError(t, eh.Handle(event))
+func encodeRecord(t *testing.T, record *eventRecord) string {
+	data, err := json.Marshal(record.Message)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestHandle(t *testing.T) {
+	eh := newEventsHandler()
+	assert.Equal(t, eh.Size(), 0)
+
+	sub, _ := eh.Subscribe(events.Filter{}, 0)
+	assert.Equal(t, eh.Size(), 1)
+
+	assert.NoError(t, eh.Handle(newTestEvent("status", "id", "from", 0)))
 
 	str := fmt.Sprintf("{%q:%q,%q:%q,%q:%q,%q:%d,%q:{%q:%q,%q:%q,%q:%q,%q:%q}}",
 		"status", "status",
@@ -53,5 +57,111 @@ func TestHandle(t *testing.T) {
 		"Addr", "node_addr",
 		"Ip", "node_ip")
 
-	assert.Equal(t, str, string(fw.Tmp))
+	assert.Equal(t, str, encodeRecord(t, <-sub.queue))
+
+	eh.Unsubscribe(sub)
+	assert.Equal(t, eh.Size(), 0)
+}
+
+func TestHandleEngineUpdate(t *testing.T) {
+	eh := newEventsHandler()
+	sub, _ := eh.Subscribe(events.Filter{}, 0)
+
+	event := newTestEvent("engine_update", "", "swarm", 0)
+	event.Changes = []string{"cpus: 2 -> 4", "label foo: added 100%"}
+	assert.NoError(t, eh.Handle(event))
+
+	changes, _ := json.Marshal(event.Changes)
+	str := fmt.Sprintf("{%q:%q,%q:%q,%q:%q,%q:%d,%q:{%q:%q,%q:%q,%q:%q,%q:%q},%q:%s}",
+		"status", "engine_update",
+		"id", "",
+		"from", "swarm node:node_name",
+		"time", 0,
+		"node",
+		"Name", "node_name",
+		"Id", "node_id",
+		"Addr", "node_addr",
+		"Ip", "node_ip",
+		"changes", changes)
+
+	assert.Equal(t, str, encodeRecord(t, <-sub.queue))
+}
+
+func TestHandleVirtualIDs(t *testing.T) {
+	eh := newEventsHandler()
+	eh.virtualIDs = true
+	sub, _ := eh.Subscribe(events.Filter{}, 0)
+
+	config := cluster.BuildContainerConfig(dockerclient.ContainerConfig{})
+	config.SetSwarmID("swarm_id")
+	event := newTestEvent("destroy", "id", "from", 0)
+	event.Container = &cluster.Container{
+		Container: dockerclient.Container{Id: "id"},
+		Config:    config,
+	}
+	assert.NoError(t, eh.Handle(event))
+
+	assert.Equal(t, (<-sub.queue).Message.ID, "swarm_id")
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	eh := newEventsHandler()
+	for i := int64(1); i <= 5; i++ {
+		assert.NoError(t, eh.Handle(newTestEvent("start", fmt.Sprintf("id%d", i), "from", i)))
+	}
+
+	// No replay unless since is set.
+	_, past := eh.Subscribe(events.Filter{}, 0)
+	assert.Len(t, past, 0)
+
+	_, past = eh.Subscribe(events.Filter{}, 3)
+	assert.Len(t, past, 3)
+	assert.Equal(t, past[0].Message.ID, "id3")
+
+	_, past = eh.Subscribe(events.Filter{"container": {"id4"}}, 1)
+	assert.Len(t, past, 1)
+	assert.Equal(t, past[0].Message.ID, "id4")
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	eh := newEventsHandler()
+	slow, _ := eh.Subscribe(events.Filter{}, 0)
+	filtered, _ := eh.Subscribe(events.Filter{"event": {"die"}}, 0)
+
+	for i := 0; i <= eventsQueueSize; i++ {
+		assert.NoError(t, eh.Handle(newTestEvent("start", "id", "from", 0)))
+	}
+
+	// The slow subscriber was dropped, its queue is closed once drained.
+	assert.Equal(t, eh.Size(), 1)
+	for range slow.queue {
+	}
+	assert.Len(t, filtered.queue, 0)
+}
+
+func TestGetEvents(t *testing.T) {
+	c := &context{eventsHandler: newEventsHandler()}
+	for i := int64(1); i <= 5; i++ {
+		assert.NoError(t, c.eventsHandler.Handle(newTestEvent("start", fmt.Sprintf("id%d", i), "from", i)))
+	}
+
+	r, _ := http.NewRequest("GET", `/events?since=2&until=4&filters={"container":["id2","id4","id5"]}`, nil)
+	w := httptest.NewRecorder()
+	getEvents(c, w, r)
+
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Header().Get("Content-Type"), "application/json")
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"id":"id2"`)
+	assert.Contains(t, lines[1], `"id":"id4"`)
+
+	// The subscriber is gone once the request returns.
+	assert.Equal(t, c.eventsHandler.Size(), 0)
+
+	r, _ = http.NewRequest("GET", "/events?since=yesterday", nil)
+	w = httptest.NewRecorder()
+	getEvents(c, w, r)
+	assert.Equal(t, w.Code, http.StatusBadRequest)
 }
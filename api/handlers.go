@@ -12,10 +12,12 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	dockerfilters "github.com/docker/docker/pkg/parsers/filters"
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/events"
 	"github.com/docker/swarm/version"
 	"github.com/gorilla/mux"
 	"github.com/samalba/dockerclient"
@@ -24,6 +26,9 @@ import (
 // The Client API version
 const APIVERSION = "1.16"
 
+// Number of engines loading an image at the same time when distributing it.
+const defaultDistributeConcurrency = 2
+
 // GET /info
 func getInfo(c *context, w http.ResponseWriter, r *http.Request) {
 	info := dockerclient.Info{
@@ -53,6 +58,258 @@ func getInfo(c *context, w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(info)
 }
 
+// GET /swarm/nodes
+func getSwarmNodes(c *context, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.cluster.Nodes())
+}
+
+// GET /swarm/nodes/{name:.*}
+func getSwarmNode(c *context, w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	node := c.cluster.Node(name)
+	if node == nil {
+		httpError(w, fmt.Sprintf("No such node: %s", name), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(node)
+}
+
+// POST /swarm/nodes/{name:.*}/labels
+func postSwarmNodeLabels(c *context, w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if c.cluster.Node(name) == nil {
+		httpError(w, fmt.Sprintf("No such node: %s", name), http.StatusNotFound)
+		return
+	}
+
+	labels := make(map[string]string)
+	if err := json.NewDecoder(r.Body).Decode(&labels); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for key := range labels {
+		if err := cluster.ValidateNodeLabel(key); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := c.cluster.UpdateNodeLabels(name, labels, nil); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.cluster.Node(name))
+}
+
+// POST /swarm/images/{name:.*}/distribute
+func postSwarmImageDistribute(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := mux.Vars(r)["name"]
+	if c.cluster.Image(name) == nil {
+		httpError(w, fmt.Sprintf("No such image: %s", name), http.StatusNotFound)
+		return
+	}
+
+	concurrency := defaultDistributeConcurrency
+	if value := r.Form.Get("concurrency"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			httpError(w, fmt.Sprintf("invalid concurrency %q", value), http.StatusBadRequest)
+			return
+		}
+		concurrency = n
+	}
+
+	// The response starts with the first progress message, so that errors
+	// found before the distribution starts are reported with a status code.
+	var (
+		wf         = NewWriteFlusher(w)
+		start      sync.Once
+		mu         sync.Mutex
+		errorFound bool
+	)
+	callback := func(where, status string, err error) {
+		start.Do(func() {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+		})
+		if err != nil {
+			mu.Lock()
+			errorFound = true
+			mu.Unlock()
+			sendJSONMessage(wf, where, fmt.Sprintf("Distributing %s... : %s", name, err.Error()))
+			return
+		}
+		sendJSONMessage(wf, where, fmt.Sprintf("Distributing %s... : %s", name, status))
+	}
+
+	if err := c.cluster.DistributeImage(name, r.Form["constraint"], concurrency, callback); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if errorFound {
+		sendErrorJSONMessage(wf, 1, "")
+	}
+}
+
+// POST /swarm/images/prune
+func postSwarmImagesPrune(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	policy := &cluster.ImagePrunePolicy{
+		Keep:   r.Form["keep"],
+		DryRun: boolValue(r, "dry-run"),
+	}
+	if value := r.Form.Get("age"); value != "" {
+		age, err := time.ParseDuration(value)
+		if err != nil || age < 0 {
+			httpError(w, fmt.Sprintf("invalid age %q", value), http.StatusBadRequest)
+			return
+		}
+		policy.MinAge = age
+	}
+	if value := r.Form.Get("keep-tags"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			httpError(w, fmt.Sprintf("invalid keep-tags %q", value), http.StatusBadRequest)
+			return
+		}
+		policy.KeepTags = n
+	}
+	// Tagged images are only removed with an explicit policy.
+	if _, ok := r.Form["dangling"]; ok {
+		policy.DanglingOnly = boolValue(r, "dangling")
+	} else {
+		policy.DanglingOnly = r.Form.Get("age") == "" && len(policy.Keep) == 0 && r.Form.Get("keep-tags") == ""
+	}
+
+	reports, err := c.cluster.PruneImages(policy)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+// POST /swarm/containers/{name:.*}/move
+func postSwarmContainerMove(c *context, w http.ResponseWriter, r *http.Request) {
+	_, container, err := getContainerFromVars(c, mux.Vars(r))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	moved, err := c.cluster.MoveContainer(container, r.Form.Get("node"))
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), "No such node"):
+			httpError(w, err.Error(), http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "Conflict"):
+			httpError(w, err.Error(), http.StatusConflict)
+		default:
+			httpError(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		ID   string `json:"Id"`
+		Node string
+	}{containerID(c.virtualIDs, moved), moved.Engine.Name})
+}
+
+// POST /swarm/containers/update
+func postSwarmContainersUpdate(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	options, err := updateOptions(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filters, err := dockerfilters.FromParam(r.Form.Get("filters"))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Updating every container of the cluster by mistake is too easy.
+	if len(filters["label"]) == 0 {
+		httpError(w, "a label filter is required", http.StatusBadRequest)
+		return
+	}
+
+	containers := []*cluster.Container{}
+	for _, container := range c.cluster.Containers() {
+		if filters.MatchKVList("label", container.Config.Labels) && filters.Match("name", strings.TrimPrefix(container.Names[0], "/")) {
+			containers = append(containers, container)
+		}
+	}
+	if len(containers) == 0 {
+		httpError(w, "No container matches the filters", http.StatusNotFound)
+		return
+	}
+	// The oldest containers are updated first.
+	sort.Sort(ContainerSorter(containers))
+
+	wf := NewWriteFlusher(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	callback := func(where, status string, err error) {
+		if err != nil {
+			sendJSONMessage(wf, where, fmt.Sprintf("Updating... : %s", err.Error()))
+			return
+		}
+		sendJSONMessage(wf, where, fmt.Sprintf("Updating... : %s", status))
+	}
+	if err := c.cluster.UpdateContainers(containers, options, callback); err != nil {
+		sendErrorJSONMessage(wf, 1, err.Error())
+	}
+}
+
+// DELETE /swarm/nodes/{name:.*}/labels/{label:.*}
+func deleteSwarmNodeLabel(c *context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	node := c.cluster.Node(name)
+	if node == nil {
+		httpError(w, fmt.Sprintf("No such node: %s", name), http.StatusNotFound)
+		return
+	}
+	if _, ok := node.Labels[cluster.NodeLabelPrefix+vars["label"]]; !ok {
+		httpError(w, fmt.Sprintf("No such label %s on node %s", vars["label"], name), http.StatusNotFound)
+		return
+	}
+
+	if err := c.cluster.UpdateNodeLabels(name, nil, []string{vars["label"]}); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // GET /version
 func getVersion(c *context, w http.ResponseWriter, r *http.Request) {
 	version := dockerclient.Version{
@@ -168,16 +425,160 @@ func getImagesJSON(c *context, w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(images)
 }
 
+// GET /networks
+func getNetworks(c *context, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.cluster.Networks().Uniq())
+}
+
+// GET /networks/{networkid:.*}
+func getNetwork(c *context, w http.ResponseWriter, r *http.Request) {
+	var id = mux.Vars(r)["networkid"]
+	network := c.cluster.Networks().Get(id)
+	if network == nil {
+		httpError(w, fmt.Sprintf("No such network: %s", id), http.StatusNotFound)
+		return
+	}
+
+	// Global networks are shown with the containers of every node.
+	for _, n := range c.cluster.Networks().Uniq() {
+		if n.ID == network.ID && (!n.IsLocal() || n.Engine == network.Engine) {
+			network = n
+			break
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(network)
+}
+
+// POST /networks/create
+func postNetworksCreate(c *context, w http.ResponseWriter, r *http.Request) {
+	var request cluster.NetworkCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := c.cluster.CreateNetwork(&request)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// POST /networks/{networkid:.*}/connect
+// POST /networks/{networkid:.*}/disconnect
+func postNetworkConnect(c *context, w http.ResponseWriter, r *http.Request) {
+	var id = mux.Vars(r)["networkid"]
+
+	var request struct {
+		Container string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	container := c.cluster.Container(request.Container)
+	if container == nil {
+		httpError(w, fmt.Sprintf("No such container: %s", request.Container), http.StatusNotFound)
+		return
+	}
+
+	// The network is the one of the node of the container: local networks
+	// such as bridge exist on every node under the same name, and global
+	// networks are on every node.
+	network := container.Engine.Networks().Get(id)
+	if network == nil {
+		if c.cluster.Networks().Get(id) != nil {
+			httpError(w, fmt.Sprintf("Network %s is not on the node of container %s", id, request.Container), http.StatusBadRequest)
+		} else {
+			httpError(w, fmt.Sprintf("No such network: %s", id), http.StatusNotFound)
+		}
+		return
+	}
+
+	var err error
+	if strings.HasSuffix(r.URL.Path, "/disconnect") {
+		err = container.Engine.DisconnectNetwork(container, network)
+	} else {
+		err = container.Engine.ConnectNetwork(container, network)
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// DELETE /networks/{networkid:.*}
+func deleteNetwork(c *context, w http.ResponseWriter, r *http.Request) {
+	var id = mux.Vars(r)["networkid"]
+	network := c.cluster.Networks().Get(id)
+	if network == nil {
+		httpError(w, fmt.Sprintf("No such network: %s", id), http.StatusNotFound)
+		return
+	}
+
+	if err := c.cluster.RemoveNetwork(network); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 // GET /volumes
 func getVolumes(c *context, w http.ResponseWriter, r *http.Request) {
 	volumes := struct {
-		Volumes []*cluster.Volume
-	}{c.cluster.Volumes()}
+		Volumes []*dockerclient.Volume
+	}{[]*dockerclient.Volume{}}
+
+	for _, volume := range c.cluster.Volumes() {
+		volumes.Volumes = append(volumes.Volumes, nodeVolume(volume))
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(volumes)
 }
 
+// POST /volumes/create
+func postVolumesCreate(c *context, w http.ResponseWriter, r *http.Request) {
+	var request cluster.VolumeCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	volume, err := c.cluster.CreateVolume(&request)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(nodeVolume(volume))
+}
+
+// DELETE /volumes/{volumename:.*}
+func deleteVolume(c *context, w http.ResponseWriter, r *http.Request) {
+	var name = mux.Vars(r)["volumename"]
+	volume := c.cluster.Volume(name)
+	if volume == nil {
+		volumeNotFound(c, w, name)
+		return
+	}
+
+	if err := c.cluster.RemoveVolume(volume); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // GET /containers/ps
 // GET /containers/json
 func getContainersJSON(c *context, w http.ResponseWriter, r *http.Request) {
@@ -242,7 +643,7 @@ func getContainersJSON(c *context, w http.ResponseWriter, r *http.Request) {
 		if !filters.Match("name", strings.TrimPrefix(container.Names[0], "/")) {
 			continue
 		}
-		if !filters.Match("id", container.Id) {
+		if !filters.Match("id", container.Id) && !(c.virtualIDs && filters.Match("id", containerID(true, container))) {
 			continue
 		}
 		if !filters.MatchKVList("label", container.Config.Labels) {
@@ -286,6 +687,7 @@ func getContainersJSON(c *context, w http.ResponseWriter, r *http.Request) {
 		// Create a copy of the underlying dockerclient.Container so we can
 		// make changes without messing with cluster.Container.
 		tmp := (*container).Container
+		tmp.Id = containerID(c.virtualIDs, container)
 
 		// Update the Status. The one we have is stale from the last `docker ps` the engine sent.
 		// `Status()` will generate a new one
@@ -309,7 +711,7 @@ func getContainersJSON(c *context, w http.ResponseWriter, r *http.Request) {
 		tmp.Ports = make([]dockerclient.Port, len(container.Ports))
 		for i, port := range container.Ports {
 			tmp.Ports[i] = port
-			if port.IP == "0.0.0.0" {
+			if isUnspecifiedIP(port.IP) {
 				tmp.Ports[i].IP = container.Engine.IP
 			}
 		}
@@ -356,8 +758,21 @@ func getContainerJSON(c *context, w http.ResponseWriter, r *http.Request) {
 	// insert Node field
 	data = bytes.Replace(data, []byte("\"Name\":\"/"), []byte(fmt.Sprintf("\"Node\":%s,\"Name\":\"/", n)), -1)
 
+	// insert RestartStatus field
+	if restartStatus := c.cluster.RestartStatus(container); restartStatus != nil {
+		status, err := json.Marshal(restartStatus)
+		if err != nil {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data = bytes.Replace(data, []byte("\"Node\":"), []byte(fmt.Sprintf("\"RestartStatus\":%s,\"Node\":", status)), 1)
+	}
+
 	// insert node IP
-	data = bytes.Replace(data, []byte("\"HostIp\":\"0.0.0.0\""), []byte(fmt.Sprintf("\"HostIp\":%q", container.Engine.IP)), -1)
+	data = replaceUnspecifiedHostIP(data, container.Engine.IP)
+
+	// expose the Swarm ID
+	data = replaceContainerID(data, container.Id, containerID(c.virtualIDs, container))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(data)
@@ -388,7 +803,7 @@ func postContainersCreate(c *context, w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	fmt.Fprintf(w, "{%q:%q}", "Id", container.Id)
+	fmt.Fprintf(w, "{%q:%q}", "Id", containerID(c.virtualIDs, container))
 	return
 }
 
@@ -435,20 +850,31 @@ func postImagesCreate(c *context, w http.ResponseWriter, r *http.Request) {
 			image += ":" + tag
 		}
 
-		errorFound := false
-		callback := func(what, status string, err error) {
+		options := &cluster.PullOptions{Constraints: r.Form["constraint"]}
+		if value := r.Form.Get("concurrency"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				sendErrorJSONMessage(wf, 1, fmt.Sprintf("invalid concurrency %q", value))
+				return
+			}
+			options.Concurrency = n
+		}
+
+		var (
+			mu         sync.Mutex
+			errorFound bool
+		)
+		callback := func(what string, message *cluster.ProgressMessage, err error) {
 			if err != nil {
+				mu.Lock()
 				errorFound = true
+				mu.Unlock()
 				sendJSONMessage(wf, what, fmt.Sprintf("Pulling %s... : %s", image, err.Error()))
 				return
 			}
-			if status == "" {
-				sendJSONMessage(wf, what, fmt.Sprintf("Pulling %s...", image))
-			} else {
-				sendJSONMessage(wf, what, fmt.Sprintf("Pulling %s... : %s", image, status))
-			}
+			sendProgressMessage(wf, what, message)
 		}
-		c.cluster.Pull(image, &authConfig, callback)
+		c.cluster.Pull(image, &authConfig, options, callback)
 
 		if errorFound {
 			sendErrorJSONMessage(wf, 1, "")
@@ -485,15 +911,76 @@ func postImagesLoad(c *context, w http.ResponseWriter, r *http.Request) {
 
 // GET /events
 func getEvents(c *context, w http.ResponseWriter, r *http.Request) {
-	c.eventsHandler.Add(r.RemoteAddr, w)
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	since, err := timestampValueOrZero(r, "since")
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	until, err := timestampValueOrZero(r, "until")
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filters, err := dockerfilters.FromParam(r.Form.Get("filters"))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sub, past := c.eventsHandler.Subscribe(events.Filter(filters), since)
+	defer c.eventsHandler.Unsubscribe(sub)
 
 	w.Header().Set("Content-Type", "application/json")
+	wf := NewWriteFlusher(w)
+	enc := json.NewEncoder(wf)
+
+	// Replay the history first.
+	for _, record := range past {
+		if until > 0 && record.Message.Time > until {
+			return
+		}
+		if err := enc.Encode(record.Message); err != nil {
+			return
+		}
+	}
+	wf.Flush()
+
+	var timeout <-chan time.Time
+	if until > 0 {
+		d := time.Unix(until, 0).Sub(time.Now())
+		if d <= 0 {
+			return
+		}
+		timeout = time.After(d)
+	}
 
-	if f, ok := w.(http.Flusher); ok {
-		f.Flush()
+	var closed <-chan bool
+	if cn, ok := w.(http.CloseNotifier); ok {
+		closed = cn.CloseNotify()
 	}
 
-	c.eventsHandler.Wait(r.RemoteAddr)
+	for {
+		select {
+		case record, ok := <-sub.queue:
+			if !ok {
+				// The subscriber was too slow and got dropped.
+				return
+			}
+			if err := enc.Encode(record.Message); err != nil {
+				return
+			}
+		case <-timeout:
+			return
+		case <-closed:
+			return
+		}
+	}
 }
 
 // POST /containers/{name:.*}/exec
@@ -581,9 +1068,21 @@ func ping(c *context, w http.ResponseWriter, r *http.Request) {
 func proxyVolume(c *context, w http.ResponseWriter, r *http.Request) {
 	var name = mux.Vars(r)["volumename"]
 	if volume := c.cluster.Volume(name); volume != nil {
+		// Remove the name of the node from the proxied URL path.
+		r.URL.Path = strings.Replace(r.URL.Path, name, volume.Name, 1)
 		proxy(c.tlsConfig, volume.Engine.Addr, w, r)
 		return
 	}
+	volumeNotFound(c, w, name)
+}
+
+// Write the error of a volume the cluster doesn't return: either there is no
+// such volume, or several nodes have one with this name.
+func volumeNotFound(c *context, w http.ResponseWriter, name string) {
+	if cluster.Volumes(c.cluster.Volumes()).Ambiguous(name) {
+		httpError(w, fmt.Sprintf("Volume %s exists on several nodes, use the node/name form (ex. node-1/%s)", name, name), http.StatusConflict)
+		return
+	}
 	httpError(w, fmt.Sprintf("No such volume: %s", name), http.StatusNotFound)
 }
 
@@ -763,10 +1262,16 @@ func postBuild(c *context, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	options, err := buildOptions(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	wf := NewWriteFlusher(w)
 
-	err := c.cluster.BuildImage(buildImage, wf)
+	err = c.cluster.BuildImage(buildImage, options, wf)
 	if err != nil {
 		httpError(w, err.Error(), http.StatusInternalServerError)
 	}
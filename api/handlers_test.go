@@ -0,0 +1,132 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/gorilla/mux"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// networksCluster is a cluster of engines serving the networks API, only
+// implementing what the networks handlers use.
+type networksCluster struct {
+	cluster.Cluster
+	engines    []*cluster.Engine
+	containers []*cluster.Container
+}
+
+func (c *networksCluster) Container(IDOrName string) *cluster.Container {
+	return cluster.Containers(c.containers).Get(IDOrName)
+}
+
+func (c *networksCluster) Networks() cluster.Networks {
+	out := cluster.Networks{}
+	for _, engine := range c.engines {
+		out = append(out, engine.Networks()...)
+	}
+	return out
+}
+
+func TestPostNetworkConnectLocal(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	c := &networksCluster{}
+	for _, name := range []string{"node-1", "node-2"} {
+		name := name
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests = append(requests, name+" "+r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			if r.URL.Path == "/networks" {
+				// Every node has its own bridge network.
+				io.WriteString(w, `[{"Name":"bridge","Id":"bridge-`+name+`","Scope":"local","Driver":"bridge"}]`)
+			}
+		}))
+		defer server.Close()
+
+		engine := cluster.NewEngine(server.Listener.Addr().String(), 0)
+		engine.Name = name
+		engine.Version = "1.9.0"
+		assert.NoError(t, engine.RefreshNetworks())
+		c.engines = append(c.engines, engine)
+	}
+	c.containers = []*cluster.Container{
+		{Container: dockerclient.Container{Id: "c1", Names: []string{"/node-2/web"}}, Engine: c.engines[1]},
+	}
+	// The bridge network is ambiguous cluster-wide.
+	assert.Nil(t, c.Networks().Get("bridge"))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/networks/{networkid:.*}/connect", func(w http.ResponseWriter, r *http.Request) {
+		postNetworkConnect(&context{cluster: c}, w, r)
+	})
+
+	// The bridge network of the node of the container is used.
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest("POST", "/networks/bridge/connect", strings.NewReader(`{"Container":"c1"}`))
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, requests, "node-2 POST /networks/bridge-node-2/connect")
+
+	// A local network of another node isn't.
+	w = httptest.NewRecorder()
+	r, err = http.NewRequest("POST", "/networks/node-1/bridge/connect", strings.NewReader(`{"Container":"c1"}`))
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r, err = http.NewRequest("POST", "/networks/missing/connect", strings.NewReader(`{"Container":"c1"}`))
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// volumesCluster is a cluster only implementing the lookup of its volumes.
+type volumesCluster struct {
+	cluster.Cluster
+	volumes cluster.Volumes
+}
+
+func (c *volumesCluster) Volumes() []*cluster.Volume {
+	return c.volumes
+}
+
+func (c *volumesCluster) Volume(name string) *cluster.Volume {
+	return c.volumes.Get(name)
+}
+
+func TestDeleteVolumeAmbiguous(t *testing.T) {
+	c := &volumesCluster{volumes: cluster.Volumes{
+		{Volume: dockerclient.Volume{Name: "data"}, Engine: &cluster.Engine{ID: "id-1", Name: "node-1"}},
+		{Volume: dockerclient.Volume{Name: "data"}, Engine: &cluster.Engine{ID: "id-2", Name: "node-2"}},
+	}}
+	router := mux.NewRouter()
+	router.HandleFunc("/volumes/{volumename:.*}", func(w http.ResponseWriter, r *http.Request) {
+		deleteVolume(&context{cluster: c}, w, r)
+	})
+
+	// The volume exists, but the node has to be given.
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest("DELETE", "/volumes/data", nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "node-1/data")
+
+	w = httptest.NewRecorder()
+	r, err = http.NewRequest("DELETE", "/volumes/missing", nil)
+	assert.NoError(t, err)
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
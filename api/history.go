@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// eventsHistory is a ring buffer of the last cluster events. It can be backed
+// by a file so that the history survives restarts. The file is written by its
+// own goroutine, so that a slow disk never blocks the events.
+type eventsHistory struct {
+	sync.Mutex
+
+	events []*eventRecord
+	next   int
+	full   bool
+
+	// Events waiting to be saved to the file.
+	queue chan *eventRecord
+	done  chan struct{}
+
+	// Only used by the goroutine saving the events.
+	file    *os.File
+	path    string
+	written int
+}
+
+func newEventsHistory(size int) *eventsHistory {
+	return &eventsHistory{
+		events: make([]*eventRecord, size),
+	}
+}
+
+// open loads the events saved in path, if any, and appends the new events
+// to it from now on.
+func (h *eventsHistory) open(path string) error {
+	h.Lock()
+	defer h.Unlock()
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			record := &eventRecord{}
+			if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+				continue
+			}
+			h.add(record)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	h.path = path
+	saved := h.ordered()
+	if err := h.compact(saved); err != nil {
+		return err
+	}
+
+	h.queue = make(chan *eventRecord, eventsQueueSize)
+	h.done = make(chan struct{})
+	go h.persist(h.queue, saved)
+	return nil
+}
+
+// close saves the queued events and closes the file.
+func (h *eventsHistory) close() {
+	h.Lock()
+	queue := h.queue
+	h.queue = nil
+	h.Unlock()
+
+	if queue != nil {
+		close(queue)
+		<-h.done
+	}
+}
+
+// Add appends an event to the history, evicting the oldest one if the
+// history is full, and queues it to be saved.
+func (h *eventsHistory) Add(record *eventRecord) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.add(record)
+	if h.queue == nil {
+		return
+	}
+	select {
+	case h.queue <- record:
+	default:
+		log.Warn("Events history queue is full, the event won't be saved")
+	}
+}
+
+// Events returns the events of the history, oldest first.
+func (h *eventsHistory) Events() []*eventRecord {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.ordered()
+}
+
+func (h *eventsHistory) ordered() []*eventRecord {
+	if !h.full {
+		return append([]*eventRecord{}, h.events[:h.next]...)
+	}
+	return append(append([]*eventRecord{}, h.events[h.next:]...), h.events[:h.next]...)
+}
+
+func (h *eventsHistory) add(record *eventRecord) {
+	h.events[h.next] = record
+	h.next = (h.next + 1) % len(h.events)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// persist saves the queued events to the file. It keeps its own copy of the
+// last saved events to compact the file without locking the history.
+func (h *eventsHistory) persist(queue <-chan *eventRecord, saved []*eventRecord) {
+	defer close(h.done)
+
+	for record := range queue {
+		saved = append(saved, record)
+		if len(saved) > len(h.events) {
+			saved = saved[1:]
+		}
+
+		// Rewrite the file once it holds twice as many events as the
+		// history so that it doesn't grow forever.
+		if h.written >= 2*len(h.events) {
+			if err := h.compact(saved); err != nil {
+				log.Errorf("Unable to compact the events history: %v", err)
+			}
+			continue
+		}
+		if err := h.write(record); err != nil {
+			log.Errorf("Unable to save event: %v", err)
+		}
+	}
+
+	if h.file != nil {
+		h.file.Close()
+	}
+}
+
+func (h *eventsHistory) write(record *eventRecord) error {
+	if h.file == nil {
+		return errors.New("no events history file")
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := h.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	h.written++
+	return nil
+}
+
+// compact rewrites the file with the given events.
+func (h *eventsHistory) compact(records []*eventRecord) error {
+	if h.file != nil {
+		h.file.Close()
+		h.file = nil
+	}
+
+	tmp := h.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	h.file = f
+	h.written = 0
+
+	for _, record := range records {
+		if err := h.write(record); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, h.path)
+}
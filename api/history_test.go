@@ -0,0 +1,61 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/swarm/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRecord(time int64) *eventRecord {
+	return &eventRecord{Message: events.Message{Status: "start", Time: time}}
+}
+
+func TestEventsHistory(t *testing.T) {
+	h := newEventsHistory(3)
+	assert.Empty(t, h.Events())
+
+	h.Add(newTestRecord(1))
+	h.Add(newTestRecord(2))
+	assert.Len(t, h.Events(), 2)
+
+	// The oldest events are evicted.
+	h.Add(newTestRecord(3))
+	h.Add(newTestRecord(4))
+	events := h.Events()
+	assert.Len(t, events, 3)
+	assert.Equal(t, events[0].Message.Time, int64(2))
+	assert.Equal(t, events[2].Message.Time, int64(4))
+}
+
+func TestEventsHistoryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.json")
+
+	h := newEventsHistory(3)
+	assert.NoError(t, h.open(path))
+	for i := int64(1); i <= 7; i++ {
+		h.Add(newTestRecord(i))
+	}
+	h.close()
+
+	// The file is compacted so that it doesn't grow forever.
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.True(t, len(strings.Split(strings.TrimSpace(string(data)), "\n")) <= 6)
+
+	// The history is loaded back from the file.
+	h = newEventsHistory(3)
+	assert.NoError(t, h.open(path))
+	events := h.Events()
+	assert.Len(t, events, 3)
+	assert.Equal(t, events[0].Message.Time, int64(5))
+	assert.Equal(t, events[2].Message.Time, int64(7))
+	h.close()
+}
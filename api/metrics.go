@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/docker/swarm/metrics"
+)
+
+var (
+	apiRequests = metrics.NewCounterVec("swarm_api_requests_total", "Number of API requests, by method and route.", "method", "route")
+	apiLatency  = metrics.NewHistogramVec("swarm_api_request_duration_seconds", "Time spent serving API requests, by method and route.", metrics.DefaultBuckets, "method", "route")
+)
+
+func init() {
+	metrics.Register(apiRequests)
+	metrics.Register(apiLatency)
+}
+
+// GET /metrics
+func getMetrics(c *context, w http.ResponseWriter, r *http.Request) {
+	families := metrics.DefaultRegistry.Gather()
+	if collector, ok := c.cluster.(metrics.Collector); ok {
+		families = append(families, collector.Collect()...)
+	}
+	families = append(families, c.eventsHandler.Collect()...)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := metrics.WriteText(w, families); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+	}
+}
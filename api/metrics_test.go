@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMetrics(t *testing.T) {
+	c := &context{eventsHandler: newEventsHandler()}
+	c.eventsHandler.Subscribe(nil, 0)
+	apiRequests.Inc("GET", "/info")
+
+	r, err := http.NewRequest("GET", "/metrics", nil)
+	assert.NoError(t, err)
+	w := httptest.NewRecorder()
+	getMetrics(c, w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; version=0.0.4", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "# TYPE swarm_api_requests_total counter\n"))
+	assert.True(t, strings.Contains(body, `swarm_api_requests_total{method="GET",route="/info"}`))
+	assert.True(t, strings.Contains(body, "swarm_events_subscribers 1\n"))
+	assert.True(t, strings.Contains(body, `swarm_events_queue_depth{consumer="api"} 0`))
+}
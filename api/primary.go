@@ -3,6 +3,7 @@ package api
 import (
 	"crypto/tls"
 	"net/http"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
@@ -16,6 +17,10 @@ type context struct {
 	statusHandler StatusHandler
 	debug         bool
 	tlsConfig     *tls.Config
+
+	// virtualIDs exposes the Swarm ID of containers instead of the ID
+	// given by the engine they run on.
+	virtualIDs bool
 }
 
 type handler func(c *context, w http.ResponseWriter, r *http.Request)
@@ -28,6 +33,9 @@ var routes = map[string]map[string]handler{
 		"/_ping":                          ping,
 		"/events":                         getEvents,
 		"/info":                           getInfo,
+		"/metrics":                        getMetrics,
+		"/swarm/nodes":                    getSwarmNodes,
+		"/swarm/nodes/{name:[^/]+}":       getSwarmNode,
 		"/version":                        getVersion,
 		"/images/json":                    getImagesJSON,
 		"/images/viz":                     notImplementedHandler,
@@ -47,39 +55,53 @@ var routes = map[string]map[string]handler{
 		"/containers/{name:.*}/stats":     proxyContainer,
 		"/containers/{name:.*}/attach/ws": proxyHijack,
 		"/exec/{execid:.*}/json":          proxyContainer,
+		"/networks":                       getNetworks,
+		"/networks/{networkid:.*}":        getNetwork,
 		"/volumes":                        getVolumes,
 		"/volumes/{volumename:.*}":        proxyVolume,
 	},
 	"POST": {
-		"/auth":                         proxyRandom,
-		"/commit":                       postCommit,
-		"/build":                        postBuild,
-		"/images/create":                postImagesCreate,
-		"/images/load":                  postImagesLoad,
-		"/images/{name:.*}/push":        proxyImageTagOptional,
-		"/images/{name:.*}/tag":         postTagImage,
-		"/containers/create":            postContainersCreate,
-		"/containers/{name:.*}/kill":    proxyContainerAndForceRefresh,
-		"/containers/{name:.*}/pause":   proxyContainerAndForceRefresh,
-		"/containers/{name:.*}/unpause": proxyContainerAndForceRefresh,
-		"/containers/{name:.*}/rename":  postRenameContainer,
-		"/containers/{name:.*}/restart": proxyContainerAndForceRefresh,
-		"/containers/{name:.*}/start":   proxyContainerAndForceRefresh,
-		"/containers/{name:.*}/stop":    proxyContainerAndForceRefresh,
-		"/containers/{name:.*}/wait":    proxyContainerAndForceRefresh,
-		"/containers/{name:.*}/resize":  proxyContainer,
-		"/containers/{name:.*}/attach":  proxyHijack,
-		"/containers/{name:.*}/copy":    proxyContainer,
-		"/containers/{name:.*}/exec":    postContainersExec,
-		"/exec/{execid:.*}/start":       proxyHijack,
-		"/exec/{execid:.*}/resize":      proxyContainer,
+		"/auth":                               proxyRandom,
+		"/commit":                             postCommit,
+		"/build":                              postBuild,
+		"/images/create":                      postImagesCreate,
+		"/images/load":                        postImagesLoad,
+		"/images/{name:.*}/push":              proxyImageTagOptional,
+		"/images/{name:.*}/tag":               postTagImage,
+		"/containers/create":                  postContainersCreate,
+		"/volumes/create":                     postVolumesCreate,
+		"/networks/create":                    postNetworksCreate,
+		"/networks/{networkid:.*}/connect":    postNetworkConnect,
+		"/networks/{networkid:.*}/disconnect": postNetworkConnect,
+		"/swarm/nodes/{name:[^/]+}/labels":    postSwarmNodeLabels,
+		"/swarm/images/{name:.*}/distribute":  postSwarmImageDistribute,
+		"/swarm/images/prune":                 postSwarmImagesPrune,
+		"/swarm/containers/{name:.*}/move":    postSwarmContainerMove,
+		"/swarm/containers/update":            postSwarmContainersUpdate,
+		"/containers/{name:.*}/kill":          proxyContainerAndForceRefresh,
+		"/containers/{name:.*}/pause":         proxyContainerAndForceRefresh,
+		"/containers/{name:.*}/unpause":       proxyContainerAndForceRefresh,
+		"/containers/{name:.*}/rename":        postRenameContainer,
+		"/containers/{name:.*}/restart":       proxyContainerAndForceRefresh,
+		"/containers/{name:.*}/start":         proxyContainerAndForceRefresh,
+		"/containers/{name:.*}/stop":          proxyContainerAndForceRefresh,
+		"/containers/{name:.*}/wait":          proxyContainerAndForceRefresh,
+		"/containers/{name:.*}/resize":        proxyContainer,
+		"/containers/{name:.*}/attach":        proxyHijack,
+		"/containers/{name:.*}/copy":          proxyContainer,
+		"/containers/{name:.*}/exec":          postContainersExec,
+		"/exec/{execid:.*}/start":             proxyHijack,
+		"/exec/{execid:.*}/resize":            proxyContainer,
 	},
 	"PUT": {
 		"/containers/{name:.*}/archive": proxyContainer,
 	},
 	"DELETE": {
-		"/containers/{name:.*}": deleteContainers,
-		"/images/{name:.*}":     deleteImages,
+		"/containers/{name:.*}":                       deleteContainers,
+		"/images/{name:.*}":                           deleteImages,
+		"/volumes/{volumename:.*}":                    deleteVolume,
+		"/networks/{networkid:.*}":                    deleteNetwork,
+		"/swarm/nodes/{name:[^/]+}/labels/{label:.*}": deleteSwarmNodeLabel,
 	},
 	"OPTIONS": {
 		"": optionsHandler,
@@ -92,10 +114,18 @@ func writeCorsHeaders(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Access-Control-Allow-Methods", "GET, POST, DELETE, PUT, OPTIONS")
 }
 
-// NewPrimary creates a new API router.
-func NewPrimary(cluster cluster.Cluster, tlsConfig *tls.Config, status StatusHandler, enableCors bool) *mux.Router {
+// NewPrimary creates a new API router. When virtualIDs is set, containers are
+// identified by their Swarm ID rather than by their engine ID. When eventsFile
+// is set, the events history is saved to it and survives restarts.
+func NewPrimary(cluster cluster.Cluster, tlsConfig *tls.Config, status StatusHandler, enableCors, virtualIDs bool, eventsFile string) *mux.Router {
 	// Register the API events handler in the cluster.
 	eventsHandler := newEventsHandler()
+	eventsHandler.virtualIDs = virtualIDs
+	if eventsFile != "" {
+		if err := eventsHandler.history.open(eventsFile); err != nil {
+			log.Errorf("Unable to use %s for the events history, keeping it in memory only: %v", eventsFile, err)
+		}
+	}
 	cluster.RegisterEventHandler(eventsHandler)
 
 	context := &context{
@@ -103,6 +133,7 @@ func NewPrimary(cluster cluster.Cluster, tlsConfig *tls.Config, status StatusHan
 		eventsHandler: eventsHandler,
 		statusHandler: status,
 		tlsConfig:     tlsConfig,
+		virtualIDs:    virtualIDs,
 	}
 
 	r := mux.NewRouter()
@@ -112,14 +143,17 @@ func NewPrimary(cluster cluster.Cluster, tlsConfig *tls.Config, status StatusHan
 
 			localRoute := route
 			localFct := fct
+			localMethod := method
 			wrap := func(w http.ResponseWriter, r *http.Request) {
 				log.WithFields(log.Fields{"method": r.Method, "uri": r.RequestURI}).Debug("HTTP request received")
+				start := time.Now()
 				if enableCors {
 					writeCorsHeaders(w, r)
 				}
 				localFct(context, w, r)
+				apiRequests.Inc(localMethod, localRoute)
+				apiLatency.Observe(time.Since(start).Seconds(), localMethod, localRoute)
 			}
-			localMethod := method
 
 			r.Path("/v{version:[0-9.]+}" + localRoute).Methods(localMethod).HandlerFunc(wrap)
 			r.Path(localRoute).Methods(localMethod).HandlerFunc(wrap)
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -8,11 +9,14 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
 )
 
 // Emit an HTTP error and log it.
@@ -34,6 +38,22 @@ func sendJSONMessage(w io.Writer, id, status string) {
 	json.NewEncoder(w).Encode(message)
 }
 
+// sendProgressMessage forwards a progress message of an engine, prefixing its
+// ID with the name of the engine.
+func sendProgressMessage(w io.Writer, where string, message *cluster.ProgressMessage) {
+	m := *message
+	if m.ID == "" {
+		m.ID = where
+	} else {
+		m.ID = where + ": " + m.ID
+	}
+	if m.ProgressDetail == nil {
+		// this is required by the docker cli to have a proper display
+		m.ProgressDetail = json.RawMessage("{}")
+	}
+	json.NewEncoder(w).Encode(m)
+}
+
 func sendErrorJSONMessage(w io.Writer, errorCode int, errorMessage string) {
 	error := struct {
 		Code    int    `json:"code,omitempty"`
@@ -58,6 +78,26 @@ func newClientAndScheme(tlsConfig *tls.Config) (*http.Client, string) {
 	return &http.Client{}, "http"
 }
 
+// containerID returns the ID under which the API exposes a container: its
+// Swarm ID when virtual IDs are enabled, its engine ID otherwise.
+func containerID(virtualIDs bool, container *cluster.Container) string {
+	if virtualIDs && container.Config != nil {
+		if swarmID := container.Config.SwarmID(); swarmID != "" {
+			return swarmID
+		}
+	}
+	return container.Id
+}
+
+// replaceContainerID rewrites the top level container ID in an inspect
+// response.
+func replaceContainerID(data []byte, id, newID string) []byte {
+	if id == newID {
+		return data
+	}
+	return bytes.Replace(data, []byte(fmt.Sprintf("%q:%q", "Id", id)), []byte(fmt.Sprintf("%q:%q", "Id", newID)), 1)
+}
+
 func getContainerFromVars(c *context, vars map[string]string) (string, *cluster.Container, error) {
 	if name, ok := vars["name"]; ok {
 		if container := c.cluster.Container(name); container != nil {
@@ -182,6 +222,22 @@ func hijack(tlsConfig *tls.Config, addr string, w http.ResponseWriter, r *http.R
 	return nil
 }
 
+// isUnspecifiedIP returns true if ip is the IPv4 or IPv6 unspecified
+// address, i.e. a port published on all interfaces.
+func isUnspecifiedIP(ip string) bool {
+	return ip == "0.0.0.0" || ip == "::"
+}
+
+// replaceUnspecifiedHostIP rewrites every "HostIp" bound to all interfaces
+// in a raw inspect payload with the given node IP.
+func replaceUnspecifiedHostIP(data []byte, ip string) []byte {
+	replacement := []byte(fmt.Sprintf("\"HostIp\":%q", ip))
+	for _, unspecified := range []string{"0.0.0.0", "::"} {
+		data = bytes.Replace(data, []byte(fmt.Sprintf("\"HostIp\":%q", unspecified)), replacement, -1)
+	}
+	return data
+}
+
 func boolValue(r *http.Request, k string) bool {
 	s := strings.ToLower(strings.TrimSpace(r.FormValue(k)))
 	return !(s == "" || s == "0" || s == "no" || s == "false" || s == "none")
@@ -202,3 +258,131 @@ func int64ValueOrZero(r *http.Request, k string) int64 {
 	}
 	return val
 }
+
+// timestampValueOrZero parses a timestamp given either as seconds since the
+// epoch (ex. 1445345627 or 1445345627.123) or as RFC 3339 (ex.
+// 2015-10-20T12:53:47Z). It returns the number of seconds since the epoch,
+// or 0 if the value is not set.
+func timestampValueOrZero(r *http.Request, k string) (int64, error) {
+	value := r.FormValue(k)
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return int64(seconds), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q for %s", value, k)
+	}
+	return t.Unix(), nil
+}
+
+// buildOptions reads the placement and the distribution of a build from the
+// query parameters, and from the build arguments so that they can be set with
+// docker build --build-arg (ex. --build-arg constraint:builder==true).
+func buildOptions(r *http.Request) (*cluster.BuildOptions, error) {
+	options := &cluster.BuildOptions{
+		Constraints:           r.Form["constraint"],
+		Affinities:            r.Form["affinity"],
+		Distribute:            boolValue(r, "distribute"),
+		DistributeConstraints: r.Form["distribute-constraint"],
+		Concurrency:           defaultDistributeConcurrency,
+	}
+	if value := r.Form.Get("concurrency"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid concurrency %q", value)
+		}
+		options.Concurrency = n
+	}
+
+	if value := r.Form.Get("buildargs"); value != "" {
+		args := make(map[string]string)
+		if err := json.Unmarshal([]byte(value), &args); err != nil {
+			return nil, fmt.Errorf("invalid buildargs: %v", err)
+		}
+		keys := make([]string, 0, len(args))
+		for key := range args {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := args[key]
+			// The client splits the argument on its first '=', which
+			// is the one of the expression's operator.
+			switch {
+			case strings.HasPrefix(key, "constraint:"):
+				options.Constraints = append(options.Constraints, strings.TrimPrefix(key, "constraint:")+"="+value)
+			case strings.HasPrefix(key, "affinity:"):
+				options.Affinities = append(options.Affinities, strings.TrimPrefix(key, "affinity:")+"="+value)
+			case key == "distribute":
+				distribute, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q for distribute", value)
+				}
+				options.Distribute = distribute
+			}
+		}
+	}
+	return options, nil
+}
+
+// Time a new container must keep running during a rolling update when the
+// monitor parameter isn't set.
+const defaultUpdateMonitor = 10 * time.Second
+
+// updateOptions reads the changes and the pace of a rolling update from the
+// query parameters.
+func updateOptions(r *http.Request) (*cluster.UpdateOptions, error) {
+	options := &cluster.UpdateOptions{
+		Image:      r.Form.Get("image"),
+		Env:        r.Form["env"],
+		Labels:     make(map[string]string),
+		BatchSize:  1,
+		Monitor:    defaultUpdateMonitor,
+		Reschedule: boolValue(r, "reschedule"),
+	}
+	for _, label := range r.Form["label"] {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) == 1 {
+			parts = append(parts, "")
+		}
+		options.Labels[parts[0]] = parts[1]
+	}
+	if options.Image == "" && len(options.Env) == 0 && len(options.Labels) == 0 {
+		return nil, errors.New("nothing to update: image, env or label is required")
+	}
+
+	if value := r.Form.Get("batch-size"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid batch-size %q", value)
+		}
+		options.BatchSize = n
+	}
+	if value := r.Form.Get("monitor"); value != "" {
+		monitor, err := time.ParseDuration(value)
+		if err != nil || monitor < 0 {
+			return nil, fmt.Errorf("invalid monitor %q", value)
+		}
+		options.Monitor = monitor
+	}
+	switch value := r.Form.Get("failure-action"); value {
+	case "", "pause":
+	case "rollback":
+		options.Rollback = true
+	default:
+		return nil, fmt.Errorf("invalid failure-action %q", value)
+	}
+	return options, nil
+}
+
+// nodeVolume returns the volume with the name of its node prepended to its
+// name, as for containers.
+func nodeVolume(volume *cluster.Volume) *dockerclient.Volume {
+	v := volume.Volume
+	v.Name = volume.Engine.Name + "/" + v.Name
+	return &v
+}
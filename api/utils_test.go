@@ -3,7 +3,12 @@ package api
 import (
 	"net/http"
 	"net/url"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
 )
 
 func TestBoolValue(t *testing.T) {
@@ -74,3 +79,127 @@ func TestInti64ValueOrZero(t *testing.T) {
 		}
 	}
 }
+
+func TestIsUnspecifiedIP(t *testing.T) {
+	cases := map[string]bool{
+		"0.0.0.0":     true,
+		"::":          true,
+		"":            false,
+		"127.0.0.1":   false,
+		"::1":         false,
+		"2001:db8::1": false,
+	}
+
+	for c, e := range cases {
+		if a := isUnspecifiedIP(c); a != e {
+			t.Fatalf("Value: %s, expected: %v, actual: %v", c, e, a)
+		}
+	}
+}
+
+func TestReplaceUnspecifiedHostIP(t *testing.T) {
+	data := []byte(`{"Ports":{"80/tcp":[{"HostIp":"0.0.0.0","HostPort":"8080"},{"HostIp":"::","HostPort":"8080"},{"HostIp":"127.0.0.1","HostPort":"8081"}]}}`)
+	expected := `{"Ports":{"80/tcp":[{"HostIp":"2001:db8::1","HostPort":"8080"},{"HostIp":"2001:db8::1","HostPort":"8080"},{"HostIp":"127.0.0.1","HostPort":"8081"}]}}`
+
+	if a := string(replaceUnspecifiedHostIP(data, "2001:db8::1")); a != expected {
+		t.Fatalf("expected: %s, actual: %s", expected, a)
+	}
+}
+
+func TestContainerID(t *testing.T) {
+	container := &cluster.Container{
+		Container: dockerclient.Container{Id: "container_id"},
+		Config:    cluster.BuildContainerConfig(dockerclient.ContainerConfig{}),
+	}
+
+	// No Swarm ID, the engine ID is always used.
+	if a := containerID(true, container); a != "container_id" {
+		t.Fatalf("expected: container_id, actual: %s", a)
+	}
+
+	container.Config.SetSwarmID("swarm_id")
+	if a := containerID(false, container); a != "container_id" {
+		t.Fatalf("expected: container_id, actual: %s", a)
+	}
+	if a := containerID(true, container); a != "swarm_id" {
+		t.Fatalf("expected: swarm_id, actual: %s", a)
+	}
+}
+
+func TestReplaceContainerID(t *testing.T) {
+	data := []byte(`{"Id":"container_id","Config":{"Labels":{"com.docker.swarm.id":"swarm_id"}}}`)
+	expected := `{"Id":"swarm_id","Config":{"Labels":{"com.docker.swarm.id":"swarm_id"}}}`
+
+	if a := string(replaceContainerID(data, "container_id", "swarm_id")); a != expected {
+		t.Fatalf("expected: %s, actual: %s", expected, a)
+	}
+}
+
+func TestBuildOptions(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/build?constraint=zone==eu&distribute=1&distribute-constraint=builder!=true", nil)
+	r.ParseForm()
+	r.Form.Set("buildargs", `{"constraint:builder":"=true","affinity:image":"=~golang","distribute":"false","version":"1.0"}`)
+
+	options, err := buildOptions(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &cluster.BuildOptions{
+		Constraints:           []string{"zone==eu", "builder==true"},
+		Affinities:            []string{"image==~golang"},
+		Distribute:            false,
+		DistributeConstraints: []string{"builder!=true"},
+		Concurrency:           defaultDistributeConcurrency,
+	}
+	if !reflect.DeepEqual(options, expected) {
+		t.Fatalf("expected: %+v, actual: %+v", expected, options)
+	}
+
+	for _, query := range []string{"concurrency=0", "buildargs=invalid", `buildargs={"distribute":"maybe"}`} {
+		r, _ := http.NewRequest("POST", "/build", nil)
+		r.Form, _ = url.ParseQuery(query)
+		if _, err := buildOptions(r); err == nil {
+			t.Fatalf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestUpdateOptions(t *testing.T) {
+	r, _ := http.NewRequest("POST", "/swarm/containers/update?image=redis:3.0&env=DEBUG=1&label=version=3&batch-size=2&monitor=10s&failure-action=rollback", nil)
+	r.ParseForm()
+
+	options, err := updateOptions(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &cluster.UpdateOptions{
+		Image:     "redis:3.0",
+		Env:       []string{"DEBUG=1"},
+		Labels:    map[string]string{"version": "3"},
+		BatchSize: 2,
+		Monitor:   10 * time.Second,
+		Rollback:  true,
+	}
+	if !reflect.DeepEqual(options, expected) {
+		t.Fatalf("expected: %+v, actual: %+v", expected, options)
+	}
+
+	// New containers are monitored by default.
+	r, _ = http.NewRequest("POST", "/swarm/containers/update?image=redis:3.0", nil)
+	r.ParseForm()
+	options, err = updateOptions(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Monitor != defaultUpdateMonitor {
+		t.Fatalf("expected monitor %s, actual: %s", defaultUpdateMonitor, options.Monitor)
+	}
+
+	for _, query := range []string{"", "image=redis&batch-size=0", "image=redis&monitor=soon", "image=redis&failure-action=retry"} {
+		r, _ := http.NewRequest("POST", "/swarm/containers/update", nil)
+		r.Form, _ = url.ParseQuery(query)
+		if _, err := updateOptions(r); err == nil {
+			t.Fatalf("%s: expected an error", query)
+		}
+	}
+}
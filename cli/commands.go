@@ -27,7 +27,7 @@ var (
 				flLeaderElection, flManageAdvertise,
 				flTLS, flTLSCaCert, flTLSCert, flTLSKey, flTLSVerify,
 				flHeartBeat,
-				flEnableCors,
+				flEnableCors, flVirtualIDs, flEventsFile, flEventSink,
 				flCluster, flClusterOpt},
 			Action: manage,
 		},
@@ -65,6 +65,19 @@ var (
 		Name:  "api-enable-cors, cors",
 		Usage: "enable CORS headers in the remote API",
 	}
+	flVirtualIDs = cli.BoolFlag{
+		Name:  "virtual-ids",
+		Usage: "identify containers by their Swarm ID in the remote API",
+	}
+	flEventsFile = cli.StringFlag{
+		Name:  "events-file",
+		Usage: "file to save the events history to, so that it can be replayed after a restart",
+	}
+	flEventSink = cli.StringSliceFlag{
+		Name:  "event-sink",
+		Usage: "send the cluster events to a sink (webhook:<url>, syslog:[<address>], file:<path>), with options and filters as ,key=value",
+		Value: &cli.StringSlice{},
+	}
 	flTLS = cli.BoolFlag{
 		Name:  "tls",
 		Usage: "use TLS; implied by --tlsverify=true",
@@ -44,6 +44,18 @@ Arguments:
 Options:
    {{range .Flags}}{{.}}
    {{end}}{{if (eq .Name "manage")}}{{printf "\t * swarm.overcommit=0.05\tovercommit to apply on resources"}}
+                                    {{printf "\t * swarm.reserved.memory=0\tmemory reserved for the system on each node"}}
+                                    {{printf "\t * swarm.reserved.cpus=0\tCPUs, possibly fractional, reserved for the system on each node"}}
+                                    {{printf "\t * swarm.image.pin=false\tresolve image names to an image ID once and create containers with it"}}
+                                    {{printf "\t * swarm.pull.concurrency=0\tmaximum number of nodes pulling an image at the same time, 0 for no limit"}}
+                                    {{printf "\t * swarm.prune.interval=0\tinterval between two removals of the unused images, 0 to disable"}}
+                                    {{printf "\t * swarm.prune.age, swarm.prune.keep, swarm.prune.keep-tags, swarm.prune.dangling\tpolicy of the scheduled image removals, untagged images only by default"}}
+                                    {{printf "\t * swarm.disk.threshold=95%%\tdata space usage (or minimum free space, ex. 10g) above which the disk filter rejects a node"}}
+                                    {{printf "\t * swarm.default.memory=0\tmemory requested by containers without -m"}}
+                                    {{printf "\t * swarm.default.cpus=0\tCPUs requested by containers without -c"}}
+                                    {{printf "\t * swarm.min.memory, swarm.max.memory, swarm.min.cpus, swarm.max.cpus\tbounds of the resources containers can request"}}
+                                    {{printf "\t * swarm.default.apply=false\twrite the default requests into the container config"}}
+                                    {{printf "\t * swarm.tenant.label=\tlabel identifying tenants with their own swarm.tenant.<tenant>.* limits"}}
                                     {{printf "\t * mesos.address=\taddress to bind on [$SWARM_MESOS_ADDRESS]"}}
                                     {{printf "\t * mesos.port=\tport to bind on [$SWARM_MESOS_PORT]"}}
                                     {{printf "\t * mesos.offertimeout=10m\ttimeout for offers [$SWARM_MESOS_OFFER_TIMEOUT]"}}
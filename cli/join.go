@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"net"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -9,9 +12,25 @@ import (
 	"github.com/docker/swarm/discovery"
 )
 
+var hostnameRegexp = regexp.MustCompile("^[0-9a-zA-Z._-]+$")
+
+// checkAddrFormat validates an ip:port or hostname:port address. IPv6
+// addresses must be enclosed in square brackets, e.g. [::1]:2375.
 func checkAddrFormat(addr string) bool {
-	m, _ := regexp.MatchString("^[0-9a-zA-Z._-]+:[0-9]{1,5}$", addr)
-	return m
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 0 || p > 65535 || len(port) > 5 {
+		return false
+	}
+	if strings.HasPrefix(addr, "[") {
+		// Only literal IPv6 addresses (with an optional zone) may be
+		// enclosed in brackets.
+		ip := strings.SplitN(host, "%", 2)[0]
+		return strings.Contains(ip, ":") && net.ParseIP(ip) != nil
+	}
+	return hostnameRegexp.MatchString(host)
 }
 
 func join(c *cli.Context) {
@@ -25,7 +44,7 @@ func join(c *cli.Context) {
 		log.Fatal("missing mandatory --advertise flag")
 	}
 	if !checkAddrFormat(addr) {
-		log.Fatal("--advertise should be of the form ip:port or hostname:port")
+		log.Fatal("--advertise should be of the form ip:port, [ipv6]:port or hostname:port")
 	}
 
 	hb, err := time.ParseDuration(c.String("heartbeat"))
@@ -24,4 +24,14 @@ func TestCheckAddrFormat(t *testing.T) {
 	assert.True(t, checkAddrFormat("1.1.1.1:1111"))
 	assert.True(t, checkAddrFormat("hostname:1111"))
 	assert.True(t, checkAddrFormat("host-name_42:1111"))
+	assert.False(t, checkAddrFormat("::1:1111"))
+	assert.False(t, checkAddrFormat("[::1]"))
+	assert.False(t, checkAddrFormat("[::1]:"))
+	assert.False(t, checkAddrFormat("[::1]:111111"))
+	assert.False(t, checkAddrFormat("[hostname]:1111"))
+	assert.False(t, checkAddrFormat("[1.1.1.1:1111"))
+	assert.False(t, checkAddrFormat("http://[::1]:1111"))
+	assert.True(t, checkAddrFormat("[::1]:1111"))
+	assert.True(t, checkAddrFormat("[2001:db8::1]:1111"))
+	assert.True(t, checkAddrFormat("[fe80::1%eth0]:1111"))
 }
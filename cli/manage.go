@@ -16,7 +16,9 @@ import (
 	"github.com/docker/swarm/cluster/swarm"
 	"github.com/docker/swarm/discovery"
 	kvdiscovery "github.com/docker/swarm/discovery/kv"
+	"github.com/docker/swarm/events"
 	"github.com/docker/swarm/leadership"
+	"github.com/docker/swarm/metrics"
 	"github.com/docker/swarm/scheduler"
 	"github.com/docker/swarm/scheduler/filter"
 	"github.com/docker/swarm/scheduler/strategy"
@@ -41,6 +43,18 @@ func (h *logHandler) Handle(e *cluster.Event) error {
 	return nil
 }
 
+// primaryAware is implemented by cluster drivers which need to know whether
+// this manager is the primary.
+type primaryAware interface {
+	SetPrimary(primary bool)
+}
+
+func setPrimary(cl cluster.Cluster, primary bool) {
+	if p, ok := cl.(primaryAware); ok {
+		p.SetPrimary(primary)
+	}
+}
+
 type statusHandler struct {
 	cluster   cluster.Cluster
 	candidate *leadership.Candidate
@@ -115,6 +129,17 @@ func createDiscovery(uri string, c *cli.Context) discovery.Discovery {
 	return discovery
 }
 
+// registerLeaderMetric exposes whether this manager is the primary. Without
+// replication, the manager is always the primary.
+func registerLeaderMetric(candidate *leadership.Candidate) {
+	metrics.Register(metrics.NewGaugeFunc("swarm_manager_leader", "Whether this manager is the primary (1) or a replica (0).", func() float64 {
+		if candidate != nil && !candidate.IsLeader() {
+			return 0
+		}
+		return 1
+	}))
+}
+
 func setupReplication(c *cli.Context, cluster cluster.Cluster, server *api.Server, discovery discovery.Discovery, addr string, tlsConfig *tls.Config) {
 	kvDiscovery, ok := discovery.(*kvdiscovery.Discovery)
 	if !ok {
@@ -125,13 +150,14 @@ func setupReplication(c *cli.Context, cluster cluster.Cluster, server *api.Serve
 
 	candidate := leadership.NewCandidate(client, p, addr)
 	follower := leadership.NewFollower(client, p)
+	registerLeaderMetric(candidate)
 
-	primary := api.NewPrimary(cluster, tlsConfig, &statusHandler{cluster, candidate, follower}, c.Bool("cors"))
+	primary := api.NewPrimary(cluster, tlsConfig, &statusHandler{cluster, candidate, follower}, c.Bool("cors"), c.Bool("virtual-ids"), c.String("events-file"))
 	replica := api.NewReplica(primary, tlsConfig)
 
 	go func() {
 		for {
-			run(candidate, server, primary, replica)
+			run(cluster, candidate, server, primary, replica)
 			time.Sleep(defaultRecoverTime)
 		}
 	}()
@@ -146,7 +172,7 @@ func setupReplication(c *cli.Context, cluster cluster.Cluster, server *api.Serve
 	server.SetHandler(primary)
 }
 
-func run(candidate *leadership.Candidate, server *api.Server, primary *mux.Router, replica *api.Replica) {
+func run(cl cluster.Cluster, candidate *leadership.Candidate, server *api.Server, primary *mux.Router, replica *api.Replica) {
 	electedCh, errCh := candidate.RunForElection()
 	for {
 		select {
@@ -158,6 +184,7 @@ func run(candidate *leadership.Candidate, server *api.Server, primary *mux.Route
 				log.Info("Leader Election: Cluster leadership lost")
 				server.SetHandler(replica)
 			}
+			setPrimary(cl, isElected)
 
 		case err := <-errCh:
 			log.Error(err)
@@ -237,6 +264,11 @@ func manage(c *cli.Context) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if val, ok := cluster.DriverOpts(c.StringSlice("cluster-opt")).String("swarm.disk.threshold", ""); ok {
+		if err := filter.SetDiskThreshold(val); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	sched := scheduler.New(s, fs)
 	var cl cluster.Cluster
@@ -253,6 +285,15 @@ func manage(c *cli.Context) {
 		log.Fatal(err)
 	}
 
+	for _, description := range c.StringSlice("event-sink") {
+		sink, err := events.NewSink(description)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cl.RegisterEventHandler(sink)
+		metrics.Register(sink)
+	}
+
 	// see https://github.com/codegangsta/cli/issues/160
 	hosts := c.StringSlice("host")
 	if c.IsSet("host") || c.IsSet("H") {
@@ -266,12 +307,14 @@ func manage(c *cli.Context) {
 			log.Fatal("--advertise address must be provided when using --leader-election")
 		}
 		if !checkAddrFormat(addr) {
-			log.Fatal("--advertise should be of the form ip:port or hostname:port")
+			log.Fatal("--advertise should be of the form ip:port, [ipv6]:port or hostname:port")
 		}
 
 		setupReplication(c, cl, server, discovery, addr, tlsConfig)
 	} else {
-		server.SetHandler(api.NewPrimary(cl, tlsConfig, &statusHandler{cl, nil, nil}, c.Bool("cors")))
+		server.SetHandler(api.NewPrimary(cl, tlsConfig, &statusHandler{cl, nil, nil}, c.Bool("cors"), c.Bool("virtual-ids"), c.String("events-file")))
+		setPrimary(cl, true)
+		registerLeaderMetric(nil)
 	}
 
 	log.Fatal(server.ListenAndServe())
@@ -0,0 +1,36 @@
+package cluster
+
+import "github.com/samalba/dockerclient"
+
+// BuildOptions places a build and distributes the image built.
+type BuildOptions struct {
+	// Constraints and affinities of the node building the image, with the
+	// syntax of the constraint and affinity filters.
+	Constraints []string
+	Affinities  []string
+	// Distribute copies the image built to the engines matching
+	// DistributeConstraints, all of them if there are none.
+	Distribute            bool
+	DistributeConstraints []string
+	// Maximum number of engines loading the image at the same time.
+	Concurrency int
+}
+
+// BuildConfig returns the config the node building the image is selected
+// with: the resources and the placement of the build.
+func BuildConfig(buildImage *dockerclient.BuildImage, options *BuildOptions) *ContainerConfig {
+	env := []string{}
+	if options != nil {
+		for _, constraint := range options.Constraints {
+			env = append(env, "constraint:"+constraint)
+		}
+		for _, affinity := range options.Affinities {
+			env = append(env, "affinity:"+affinity)
+		}
+	}
+	return BuildContainerConfig(dockerclient.ContainerConfig{
+		CpuShares: buildImage.CpuShares,
+		Memory:    buildImage.Memory,
+		Env:       env,
+	})
+}
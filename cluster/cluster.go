@@ -37,11 +37,27 @@ type Cluster interface {
 	// Return one volume from the cluster
 	Volume(name string) *Volume
 
-	// Pull images
+	// Create a volume on a node matching its placement
+	CreateVolume(request *VolumeCreateRequest) (*Volume, error)
+
+	// Remove a volume from its node
+	RemoveVolume(volume *Volume) error
+
+	// Return the networks of every node
+	Networks() Networks
+
+	// Create a network on a node
+	CreateNetwork(request *NetworkCreateRequest) (*NetworkCreateResponse, error)
+
+	// Remove a network
+	RemoveNetwork(network *Network) error
+
+	// Pull images on the engines matching `options`
 	// `callback` can be called multiple time
 	//  `where` is where it is being pulled
-	//  `status` is the current status, like "", "in progress" or "downloaded
-	Pull(name string, authConfig *dockerclient.AuthConfig, callback func(where, status string, err error))
+	//  `message` is the progress message of the engine, like "Pulling...",
+	//  the progress of a layer or "downloaded"
+	Pull(name string, authConfig *dockerclient.AuthConfig, options *PullOptions, callback func(where string, message *ProgressMessage, err error))
 
 	// Import image
 	// `callback` can be called multiple time
@@ -55,6 +71,27 @@ type Cluster interface {
 	// `status` is the current status, like "", "in progress" or "loaded"
 	Load(imageReader io.Reader, callback func(what, status string))
 
+	// Copy the image `name` from an engine which has it to the engines
+	// matching `constraints`, `concurrency` engines at a time
+	// `callback` can be called multiple time
+	//  `where` is the engine the image is copied to
+	//  `status` is the current status, like "Waiting", "Copying" or "Loaded"
+	DistributeImage(name string, constraints []string, concurrency int, callback func(where, status string, err error)) error
+
+	// Remove the images selected by `policy` from every engine
+	// Return a report of the removed images per engine
+	PruneImages(policy *ImagePrunePolicy) ([]*ImagePruneReport, error)
+
+	// Return the description of every node of the cluster
+	Nodes() []*NodeInfo
+
+	// Return the node matching `IDOrName`
+	Node(IDOrName string) *NodeInfo
+
+	// Add or update the labels `labels` set by the manager on a node and
+	// remove the labels `removed`
+	UpdateNodeLabels(IDOrName string, labels map[string]string, removed []string) error
+
 	// Return some info about the cluster, like nb or containers / images
 	// It is pretty open, so the implementation decides what to return.
 	Info() [][]string
@@ -65,7 +102,8 @@ type Cluster interface {
 	// Return the number of CPUs in the cluster
 	TotalCpus() int64
 
-	// Register an event handler for cluster-wide events.
+	// Register an event handler for cluster-wide events. Several handlers
+	// can be registered, each of them receives every event.
 	RegisterEventHandler(h EventHandler) error
 
 	// FIXME: remove this method
@@ -75,8 +113,26 @@ type Cluster interface {
 	// RenameContainer rename a container
 	RenameContainer(container *Container, newName string) error
 
-	// BuildImage build an image
-	BuildImage(*dockerclient.BuildImage, io.Writer) error
+	// RestartStatus returns what the cluster-level restart policy did to
+	// `container`, nil if it never acted on it
+	RestartStatus(container *Container) *RestartStatus
+
+	// MoveContainer recreates a container on the node matching
+	// `nodeIDOrName`, or on any other node if it is empty, and removes it
+	// from its node
+	MoveContainer(container *Container, nodeIDOrName string) (*Container, error)
+
+	// UpdateContainers recreates `containers` with the changes of `options`,
+	// a batch of containers at a time
+	// `callback` can be called multiple time
+	//  `where` is the name of the container being updated
+	//  `status` is the current status, like "Waiting", "Running on node-1"
+	//  or "Updated"
+	UpdateContainers(containers []*Container, options *UpdateOptions, callback func(where, status string, err error)) error
+
+	// BuildImage build an image on a node matching the placement of the
+	// options, and distributes it if asked to
+	BuildImage(*dockerclient.BuildImage, *BuildOptions, io.Writer) error
 
 	// TagImage tag an image
 	TagImage(IDOrName string, repo string, tag string, force bool) error
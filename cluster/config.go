@@ -2,8 +2,11 @@ package cluster
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"strings"
 
+	"github.com/docker/docker/pkg/units"
 	"github.com/samalba/dockerclient"
 )
 
@@ -158,3 +161,104 @@ func (c *ContainerConfig) AddAffinity(affinity string) error {
 	c.Labels[SwarmLabelNamespace+".affinities"] = string(labels)
 	return nil
 }
+
+// SwarmRestartPolicy returns the cluster-level restart policy from the
+// Config. May return nil if not set.
+func (c *ContainerConfig) SwarmRestartPolicy() (*RestartPolicy, error) {
+	value, ok := c.Labels[SwarmLabelNamespace+".restart"]
+	if !ok {
+		return nil, nil
+	}
+	return ParseRestartPolicy(value)
+}
+
+// MilliCpus returns the CPUs requested by the container, in thousandths of a
+// CPU. The request comes from the com.docker.swarm.cpus label, from the CPU
+// quota and period, or from CpuShares, which is a number of CPUs.
+func (c *ContainerConfig) MilliCpus() int64 {
+	if value, ok := c.Labels[SwarmLabelNamespace+".cpus"]; ok {
+		if milliCpus, err := ParseCpus(value); err == nil {
+			return milliCpus
+		}
+	}
+	if c.HostConfig.CpuQuota > 0 {
+		period := c.HostConfig.CpuPeriod
+		if period <= 0 {
+			period = defaultCpuPeriod
+		}
+		return int64(math.Ceil(float64(c.HostConfig.CpuQuota) * 1000 / float64(period)))
+	}
+	return c.CpuShares * 1000
+}
+
+// SetMilliCpus sets or overrides the CPUs requested by the container, in
+// thousandths of a CPU.
+func (c *ContainerConfig) SetMilliCpus(milliCpus int64) {
+	c.Labels[SwarmLabelNamespace+".cpus"] = FormatCpus(milliCpus)
+}
+
+// ReservedMemory returns the memory the scheduler reserves for the container:
+// the com.docker.swarm.reserve.memory label if set, its memory limit
+// otherwise. Reserving less than the limit lets the container burst.
+func (c *ContainerConfig) ReservedMemory() int64 {
+	if value, ok := c.Labels[SwarmLabelNamespace+".reserve.memory"]; ok {
+		if memory, err := units.RAMInBytes(value); err == nil && memory >= 0 {
+			return memory
+		}
+	}
+	return c.Memory
+}
+
+// ReservedMilliCpus returns the CPUs the scheduler reserves for the
+// container, in thousandths of a CPU: the com.docker.swarm.reserve.cpu label
+// if set, the CPUs it requests otherwise.
+func (c *ContainerConfig) ReservedMilliCpus() int64 {
+	if value, ok := c.Labels[SwarmLabelNamespace+".reserve.cpu"]; ok {
+		if milliCpus, err := ParseCpus(value); err == nil {
+			return milliCpus
+		}
+	}
+	return c.MilliCpus()
+}
+
+// ValidateReservations checks the reservation labels of the container: they
+// must be valid and can't exceed the limits of the container, if any.
+func (c *ContainerConfig) ValidateReservations() error {
+	if value, ok := c.Labels[SwarmLabelNamespace+".reserve.memory"]; ok {
+		memory, err := units.RAMInBytes(value)
+		if err != nil || memory < 0 {
+			return fmt.Errorf("invalid memory reservation %q", value)
+		}
+		if c.Memory > 0 && memory > c.Memory {
+			return fmt.Errorf("memory reservation %s exceeds the memory limit %s", units.BytesSize(float64(memory)), units.BytesSize(float64(c.Memory)))
+		}
+	}
+	if value, ok := c.Labels[SwarmLabelNamespace+".reserve.cpu"]; ok {
+		milliCpus, err := ParseCpus(value)
+		if err != nil {
+			return fmt.Errorf("invalid CPU reservation %q", value)
+		}
+		if limit := c.MilliCpus(); limit > 0 && milliCpus > limit {
+			return fmt.Errorf("CPU reservation %s exceeds the CPU limit %s", FormatCpus(milliCpus), FormatCpus(limit))
+		}
+	}
+	return nil
+}
+
+// ImageName returns the image the container was requested with, before it
+// was pinned to an image ID.
+func (c *ContainerConfig) ImageName() string {
+	if name, ok := c.Labels[SwarmLabelNamespace+".image"]; ok && name != "" {
+		return name
+	}
+	return c.Image
+}
+
+// PinImage replaces the image of the container with an image ID so that every
+// engine runs the same image, and keeps the name it was requested with.
+func (c *ContainerConfig) PinImage(id string) {
+	if _, ok := c.Labels[SwarmLabelNamespace+".image"]; !ok {
+		c.Labels[SwarmLabelNamespace+".image"] = c.Image
+	}
+	c.Image = id
+}
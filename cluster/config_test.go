@@ -89,3 +89,96 @@ func TestAddAffinity(t *testing.T) {
 	config.AddAffinity("image==~testimage")
 	assert.Len(t, config.Affinities(), 1)
 }
+
+func TestSwarmRestartPolicy(t *testing.T) {
+	config := BuildContainerConfig(dockerclient.ContainerConfig{})
+	policy, err := config.SwarmRestartPolicy()
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+
+	config = BuildContainerConfig(dockerclient.ContainerConfig{Labels: map[string]string{SwarmLabelNamespace + ".restart": "on-failure:5,relocate-after=3"}})
+	policy, err = config.SwarmRestartPolicy()
+	assert.NoError(t, err)
+	assert.Equal(t, policy, &RestartPolicy{Name: "on-failure", MaximumRetryCount: 5, RelocateAfter: 3})
+
+	config = BuildContainerConfig(dockerclient.ContainerConfig{Labels: map[string]string{SwarmLabelNamespace + ".restart": "never"}})
+	_, err = config.SwarmRestartPolicy()
+	assert.Error(t, err)
+}
+
+func TestParseCpus(t *testing.T) {
+	milliCpus, err := ParseCpus("2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000), milliCpus)
+
+	milliCpus, err = ParseCpus("0.25")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), milliCpus)
+	assert.Equal(t, "0.25", FormatCpus(milliCpus))
+
+	_, err = ParseCpus("-1")
+	assert.Error(t, err)
+	_, err = ParseCpus("lots")
+	assert.Error(t, err)
+}
+
+func TestSharesToMilliCpus(t *testing.T) {
+	assert.Equal(t, int64(0), sharesToMilliCpus(0, 4))
+	assert.Equal(t, int64(500), sharesToMilliCpus(128, 4))
+	assert.Equal(t, int64(2000), sharesToMilliCpus(512, 4))
+
+	// Requests round-trip through shares.
+	for _, cpus := range []int64{1, 4, 12, 48} {
+		for _, milliCpus := range []int64{100, 250, 500, 1000, 1500} {
+			if milliCpus <= cpus*1000 {
+				assert.Equal(t, milliCpus, sharesToMilliCpus(milliCpusToShares(milliCpus, cpus), cpus))
+			}
+		}
+	}
+}
+
+func TestMilliCpus(t *testing.T) {
+	config := BuildContainerConfig(dockerclient.ContainerConfig{})
+	assert.Equal(t, int64(0), config.MilliCpus())
+
+	// CpuShares is a number of CPUs.
+	config = BuildContainerConfig(dockerclient.ContainerConfig{CpuShares: 2})
+	assert.Equal(t, int64(2000), config.MilliCpus())
+
+	// The quota takes precedence over the shares.
+	config = BuildContainerConfig(dockerclient.ContainerConfig{CpuShares: 2, HostConfig: dockerclient.HostConfig{CpuQuota: 50000, CpuPeriod: 100000}})
+	assert.Equal(t, int64(500), config.MilliCpus())
+	config = BuildContainerConfig(dockerclient.ContainerConfig{HostConfig: dockerclient.HostConfig{CpuQuota: 150000}})
+	assert.Equal(t, int64(1500), config.MilliCpus())
+
+	// The label takes precedence over everything.
+	config.SetMilliCpus(750)
+	assert.Equal(t, "0.75", config.Labels[SwarmLabelNamespace+".cpus"])
+	assert.Equal(t, int64(750), config.MilliCpus())
+}
+
+func TestReservations(t *testing.T) {
+	// Without reservation labels, the limits are reserved.
+	config := BuildContainerConfig(dockerclient.ContainerConfig{Memory: 2 * 1024 * 1024 * 1024, CpuShares: 2})
+	assert.Equal(t, int64(2*1024*1024*1024), config.ReservedMemory())
+	assert.Equal(t, int64(2000), config.ReservedMilliCpus())
+	assert.NoError(t, config.ValidateReservations())
+
+	config.Labels[SwarmLabelNamespace+".reserve.memory"] = "512m"
+	config.Labels[SwarmLabelNamespace+".reserve.cpu"] = "0.5"
+	assert.Equal(t, int64(512*1024*1024), config.ReservedMemory())
+	assert.Equal(t, int64(500), config.ReservedMilliCpus())
+	assert.NoError(t, config.ValidateReservations())
+
+	// Reservations can't exceed the limits.
+	config.Labels[SwarmLabelNamespace+".reserve.cpu"] = "3"
+	assert.Error(t, config.ValidateReservations())
+	config.Labels[SwarmLabelNamespace+".reserve.cpu"] = "lots"
+	assert.Error(t, config.ValidateReservations())
+	assert.Equal(t, int64(2000), config.ReservedMilliCpus())
+
+	// Containers without limits can reserve anything.
+	config = BuildContainerConfig(dockerclient.ContainerConfig{Labels: map[string]string{SwarmLabelNamespace + ".reserve.memory": "1g"}})
+	assert.NoError(t, config.ValidateReservations())
+	assert.Equal(t, int64(1024*1024*1024), config.ReservedMemory())
+}
@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// defaultCpuPeriod is the CFS period used by Docker when only a quota is set,
+// in microseconds.
+const defaultCpuPeriod = 100000
+
+// ParseCpus parses a number of CPUs, which can be fractional (ex. 0.5), into
+// thousandths of a CPU.
+func ParseCpus(value string) (int64, error) {
+	cpus, err := strconv.ParseFloat(value, 64)
+	if err != nil || cpus < 0 || math.IsInf(cpus, 0) || math.IsNaN(cpus) {
+		return 0, fmt.Errorf("invalid number of CPUs %q", value)
+	}
+	return int64(math.Ceil(cpus * 1000)), nil
+}
+
+// FormatCpus formats thousandths of a CPU as a number of CPUs.
+func FormatCpus(milliCpus int64) string {
+	return strconv.FormatFloat(float64(milliCpus)/1000, 'f', -1, 64)
+}
+
+// milliCpusToShares converts thousandths of a CPU to the CPU shares of an
+// engine with cpus CPUs.
+func milliCpusToShares(milliCpus, cpus int64) int64 {
+	if cpus <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(float64(milliCpus*1024) / float64(cpus*1000)))
+}
+
+// sharesToMilliCpus converts the CPU shares of an engine with cpus CPUs to
+// thousandths of a CPU. Shares are coarser than thousandths of a CPU, so it
+// returns the roundest value which milliCpusToShares converts back to the
+// same shares: 0.5 CPU stays 0.5 CPU rather than becoming 0.498.
+func sharesToMilliCpus(shares, cpus int64) int64 {
+	if shares <= 0 || cpus <= 0 {
+		return 0
+	}
+	low := (shares-1)*1000*cpus/1024 + 1
+	high := shares * 1000 * cpus / 1024
+	for _, unit := range []int64{1000, 500, 250, 100, 50, 25, 10, 5} {
+		if milliCpus := high / unit * unit; milliCpus >= low {
+			return milliCpus
+		}
+	}
+	return high
+}
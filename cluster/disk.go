@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var diskSizeRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([kKmMgGtTpPeE]?)(i?)[bB]$`)
+
+// parseDiskSize parses the sizes reported by the storage drivers, either
+// decimal (ex. "107.4 GB") or binary (ex. "2 GiB").
+func parseDiskSize(value string) (int64, error) {
+	matches := diskSizeRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	size, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	base := 1000.0
+	if matches[3] != "" {
+		base = 1024.0
+	}
+	if prefix := strings.ToLower(matches[2]); prefix != "" {
+		for _, p := range "kmgtpe" {
+			size *= base
+			if string(p) == prefix {
+				break
+			}
+		}
+	}
+	return int64(size), nil
+}
+
+// parseDiskSpace extracts the data space used and total from the status of
+// the storage driver, as reported by devicemapper and similar drivers. It
+// returns 0 for drivers which don't report their space.
+func parseDiskSpace(status [][]string) (used, total int64) {
+	var available int64
+	for _, kv := range status {
+		if len(kv) != 2 {
+			continue
+		}
+		size, err := parseDiskSize(kv[1])
+		if err != nil {
+			continue
+		}
+		switch kv[0] {
+		case "Data Space Used":
+			used = size
+		case "Data Space Total":
+			total = size
+		case "Data Space Available":
+			available = size
+		}
+	}
+
+	// Thin pools can be smaller than their nominal size once the underlying
+	// filesystem is full: the space left is the one actually available.
+	if total > 0 && available > 0 && used+available < total {
+		total = used + available
+	}
+	return used, total
+}
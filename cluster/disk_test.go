@@ -0,0 +1,70 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseDiskSize(t *testing.T) {
+	size, err := parseDiskSize("107.4 GB")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(107400000000), size)
+
+	size, err = parseDiskSize("2 GiB")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2*1024*1024*1024), size)
+
+	size, err = parseDiskSize("512 B")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(512), size)
+
+	_, err = parseDiskSize("lots")
+	assert.Error(t, err)
+}
+
+func TestParseDiskSpace(t *testing.T) {
+	// Drivers which don't report their space.
+	used, total := parseDiskSpace([][]string{{"Root Dir", "/var/lib/docker/aufs"}, {"Dirs", "42"}})
+	assert.Equal(t, int64(0), used)
+	assert.Equal(t, int64(0), total)
+
+	used, total = parseDiskSpace([][]string{
+		{"Pool Name", "docker-202:1-1032-pool"},
+		{"Data Space Used", "1.2 GB"},
+		{"Data Space Total", "107.4 GB"},
+		{"Data Space Available", "106.2 GB"},
+		{"Metadata Space Used", "2.2 MB"},
+	})
+	assert.Equal(t, int64(1200000000), used)
+	assert.Equal(t, int64(107400000000), total)
+
+	// The space available is capped by the underlying filesystem.
+	used, total = parseDiskSpace([][]string{
+		{"Data Space Used", "10 GB"},
+		{"Data Space Total", "107.4 GB"},
+		{"Data Space Available", "5 GB"},
+	})
+	assert.Equal(t, int64(10000000000), used)
+	assert.Equal(t, int64(15000000000), total)
+}
+
+func TestEngineDiskSpace(t *testing.T) {
+	engine := NewEngine("test", 0)
+
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(&dockerclient.Info{ID: "id", Name: "name", NCPU: 1, MemTotal: 1024, DriverStatus: [][]string{{"Data Space Used", "1 GB"}, {"Data Space Total", "10 GB"}}}, nil)
+	client.On("Version").Return(mockVersion, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{}, nil)
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	assert.NoError(t, engine.ConnectWithClient(client))
+
+	assert.Equal(t, int64(1000000000), engine.DiskUsed)
+	assert.Equal(t, int64(10000000000), engine.DiskTotal)
+	assert.Equal(t, int64(10000000000), NewNodeInfo(engine).Resources.Disk)
+}
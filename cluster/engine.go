@@ -1,12 +1,19 @@
 package cluster
 
 import (
+	"bytes"
 	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"math"
+	"io/ioutil"
 	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -21,11 +28,17 @@ const (
 	// Force-refresh the state of the engine this often.
 	stateRefreshPeriod = 30 * time.Second
 
+	// Refresh the specs (labels, CPUs, memory, ...) of the engine this often.
+	specsRefreshPeriod = 2 * time.Minute
+
 	// Timeout for requests sent out to the engine.
 	requestTimeout = 10 * time.Second
 
 	// Minimum docker engine version supported by swarm.
 	minSupportedVersion = version.Version("1.6.0")
+
+	// Minimum docker engine version with the networks API.
+	minNetworksVersion = version.Version("1.9.0")
 )
 
 // NewEngine is exported
@@ -46,22 +59,38 @@ func NewEngine(addr string, overcommitRatio float64) *Engine {
 type Engine struct {
 	sync.RWMutex
 
-	ID     string
-	IP     string
-	Addr   string
-	Name   string
-	Cpus   int64
-	Memory int64
-	Labels map[string]string
-
-	stopCh          chan struct{}
-	containers      map[string]*Container
-	images          []*Image
-	volumes         []*Volume
-	client          dockerclient.Client
-	eventHandler    EventHandler
-	healthy         bool
-	overcommitRatio int64
+	ID      string
+	IP      string
+	Addr    string
+	Name    string
+	Cpus    int64
+	Memory  int64
+	Labels  map[string]string
+	Version string
+
+	// Data space of the storage driver, when it reports it. Both are 0
+	// otherwise.
+	DiskUsed  int64
+	DiskTotal int64
+
+	stopCh            chan struct{}
+	nodeLabels        map[string]string
+	specsUpdatedAt    time.Time
+	updatedAt         time.Time
+	containers        map[string]*Container
+	images            []*Image
+	volumes           []*Volume
+	networks          []*Network
+	client            dockerclient.Client
+	eventHandler      EventHandler
+	healthy           bool
+	overcommitRatio   int64
+	reservedMemory    int64
+	reservedMilliCpus int64
+	resourcePolicy    *ResourcePolicy
+	imagePlatforms    map[string]Platform
+	tlsConfig         *tls.Config
+	transport         *http.Transport
 }
 
 // Connect will initialize a connection to the Docker daemon running on the
@@ -72,11 +101,14 @@ func (e *Engine) Connect(config *tls.Config) error {
 		return err
 	}
 
-	addr, err := net.ResolveIPAddr("ip4", host)
+	// Resolve the IP (v4 or v6) of the engine but keep Addr untouched so that
+	// hostnames are preserved in the cluster state.
+	addr, err := net.ResolveIPAddr("ip", host)
 	if err != nil {
 		return err
 	}
 	e.IP = addr.IP.String()
+	e.tlsConfig = config
 
 	c, err := dockerclient.NewDockerClientTimeout("tcp://"+e.Addr, config, time.Duration(requestTimeout))
 	if err != nil {
@@ -90,22 +122,20 @@ func (e *Engine) Connect(config *tls.Config) error {
 func (e *Engine) ConnectWithClient(client dockerclient.Client) error {
 	e.client = client
 
-	// Fetch the engine labels.
-	if err := e.updateSpecs(); err != nil {
-		return err
-	}
-
-	// Force a state update before returning.
-	if err := e.RefreshContainers(true); err != nil {
-		return err
-	}
-
-	if err := e.RefreshImages(); err != nil {
+	if err := e.fetchState(); err != nil {
+		// Stay disconnected: the client doesn't monitor events, it
+		// couldn't be stopped by Disconnect.
+		e.client = nopclient.NewNopClient()
 		return err
 	}
 
 	// Do not check error as older daemon don't support this call
 	e.RefreshVolumes()
+	e.RefreshNetworks()
+
+	// The engine may have been restored from a snapshot as unhealthy.
+	e.setHealthy(true)
+	e.setUpdatedAt()
 
 	// Start the update loop.
 	go e.refreshLoop()
@@ -117,6 +147,22 @@ func (e *Engine) ConnectWithClient(client dockerclient.Client) error {
 	return nil
 }
 
+// fetchState gathers the specs and the state of the engine the first time it
+// is connected.
+func (e *Engine) fetchState() error {
+	// Fetch the engine labels.
+	if _, err := e.updateSpecs(); err != nil {
+		return err
+	}
+
+	// Force a state update before returning.
+	if err := e.RefreshContainers(true); err != nil {
+		return err
+	}
+
+	return e.RefreshImages()
+}
+
 // Disconnect will stop all monitoring of the engine.
 // The Engine object cannot be further used without reconnecting it first.
 func (e *Engine) Disconnect() {
@@ -127,6 +173,9 @@ func (e *Engine) Disconnect() {
 	close(e.stopCh)
 	e.client.StopAllMonitorEvents()
 	e.client = nopclient.NewNopClient()
+	if e.transport != nil {
+		e.transport.CloseIdleConnections()
+	}
 	e.emitEvent("engine_disconnect")
 }
 
@@ -138,23 +187,36 @@ func (e *Engine) isConnected() bool {
 
 // IsHealthy returns true if the engine is healthy
 func (e *Engine) IsHealthy() bool {
+	e.RLock()
+	defer e.RUnlock()
 	return e.healthy
 }
 
+// setHealthy flags the engine as healthy or not, and returns whether it was
+// healthy.
+func (e *Engine) setHealthy(healthy bool) bool {
+	e.Lock()
+	defer e.Unlock()
+	was := e.healthy
+	e.healthy = healthy
+	return was
+}
+
 // Gather engine specs (CPU, memory, constraints, ...).
-func (e *Engine) updateSpecs() error {
+// Returns a description of every spec that changed since the last update.
+func (e *Engine) updateSpecs() ([]string, error) {
 	info, err := e.client.Info()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if info.NCPU == 0 || info.MemTotal == 0 {
-		return fmt.Errorf("cannot get resources for this engine, make sure %s is a Docker Engine, not a Swarm manager", e.Addr)
+		return nil, fmt.Errorf("cannot get resources for this engine, make sure %s is a Docker Engine, not a Swarm manager", e.Addr)
 	}
 
 	v, err := e.client.Version()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	engineVersion := version.Version(v.Version)
@@ -162,23 +224,160 @@ func (e *Engine) updateSpecs() error {
 	// Older versions of Docker don't expose the ID field, Labels and are not supported
 	// by Swarm.  Catch the error ASAP and refuse to connect.
 	if engineVersion.LessThan(minSupportedVersion) {
-		return fmt.Errorf("engine %s is running an unsupported version of Docker Engine. Please upgrade to at least %s", e.Addr, minSupportedVersion)
+		return nil, fmt.Errorf("engine %s is running an unsupported version of Docker Engine. Please upgrade to at least %s", e.Addr, minSupportedVersion)
 	}
 
-	e.ID = info.ID
-	e.Name = info.Name
-	e.Cpus = info.NCPU
-	e.Memory = info.MemTotal
-	e.Labels = map[string]string{
+	labels := map[string]string{
 		"storagedriver":   info.Driver,
 		"executiondriver": info.ExecutionDriver,
 		"kernelversion":   info.KernelVersion,
 		"operatingsystem": info.OperatingSystem,
+		"engineversion":   v.Version,
+		"memorylimit":     strconv.FormatBool(infoBool(info.MemoryLimit)),
+		"swaplimit":       strconv.FormatBool(infoBool(info.SwapLimit)),
+	}
+	if v.Os != "" {
+		labels["ostype"] = v.Os
+	}
+	if v.Arch != "" {
+		labels["architecture"] = normalizeArchitecture(v.Arch)
 	}
 	for _, label := range info.Labels {
 		kv := strings.SplitN(label, "=", 2)
-		e.Labels[kv[0]] = kv[1]
+		labels[kv[0]] = kv[1]
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	labels = mergeNodeLabels(labels, e.nodeLabels)
+
+	var changes []string
+	// Only report changes once the engine has been fully initialized.
+	if e.ID != "" {
+		changes = diffSpecs(e, info, labels)
+	}
+
+	e.ID = info.ID
+	e.Name = info.Name
+	e.Cpus = info.NCPU
+	e.Memory = info.MemTotal
+	e.DiskUsed, e.DiskTotal = parseDiskSpace(info.DriverStatus)
+	e.Labels = labels
+	e.Version = v.Version
+	e.specsUpdatedAt = time.Now()
+	return changes, nil
+}
+
+// SetNodeLabels replaces the labels set on the engine by the manager. They
+// are merged into Labels, prefixed with NodeLabelPrefix.
+func (e *Engine) SetNodeLabels(labels map[string]string) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.nodeLabels = labels
+	e.Labels = mergeNodeLabels(e.Labels, labels)
+}
+
+// NodeLabels returns the labels set on the engine by the manager, without
+// their prefix.
+func (e *Engine) NodeLabels() map[string]string {
+	e.RLock()
+	defer e.RUnlock()
+
+	labels := make(map[string]string, len(e.nodeLabels))
+	for k, v := range e.nodeLabels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// mergeNodeLabels returns a copy of the engine labels where the manager
+// labels replace the previous ones. Labels of the daemon can't use the
+// manager prefix.
+func mergeNodeLabels(labels, nodeLabels map[string]string) map[string]string {
+	merged := make(map[string]string, len(labels)+len(nodeLabels))
+	for k, v := range labels {
+		if !strings.HasPrefix(k, NodeLabelPrefix) {
+			merged[k] = v
+		}
 	}
+	for k, v := range nodeLabels {
+		merged[NodeLabelPrefix+k] = v
+	}
+	return merged
+}
+
+// diffSpecs describes the differences between the current specs of the
+// engine and the ones freshly reported by the daemon.
+func diffSpecs(e *Engine, info *dockerclient.Info, labels map[string]string) []string {
+	changes := []string{}
+	if e.ID != info.ID {
+		changes = append(changes, fmt.Sprintf("id: %s -> %s", e.ID, info.ID))
+	}
+	if e.Name != info.Name {
+		changes = append(changes, fmt.Sprintf("name: %s -> %s", e.Name, info.Name))
+	}
+	if e.Cpus != info.NCPU {
+		changes = append(changes, fmt.Sprintf("cpus: %d -> %d", e.Cpus, info.NCPU))
+	}
+	if e.Memory != info.MemTotal {
+		changes = append(changes, fmt.Sprintf("memory: %d -> %d", e.Memory, info.MemTotal))
+	}
+
+	keys := []string{}
+	for k := range e.Labels {
+		keys = append(keys, k)
+	}
+	for k := range labels {
+		if _, ok := e.Labels[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		old, existed := e.Labels[k]
+		value, exists := labels[k]
+		switch {
+		case !existed:
+			changes = append(changes, fmt.Sprintf("label %s: added %s", k, value))
+		case !exists:
+			changes = append(changes, fmt.Sprintf("label %s: removed", k))
+		case old != value:
+			changes = append(changes, fmt.Sprintf("label %s: %s -> %s", k, old, value))
+		}
+	}
+	return changes
+}
+
+// refreshSpecs updates the engine specs and notifies the event handler if
+// anything changed. If the engine ID changed (e.g. Docker was reinstalled
+// at the same address), the whole state is refreshed.
+func (e *Engine) refreshSpecs() error {
+	oldID := e.ID
+	changes, err := e.updateSpecs()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	log.WithFields(log.Fields{"name": e.Name, "id": e.ID}).Infof("Engine specs changed: %s", strings.Join(changes, ", "))
+
+	if e.ID != oldID {
+		// This is a brand new daemon, nothing we know about it is valid.
+		e.cleanupContainers()
+		if err := e.RefreshContainers(true); err != nil {
+			return err
+		}
+		e.RefreshImages()
+		e.RefreshVolumes()
+		e.RefreshNetworks()
+	}
+
+	e.emitUpdateEvent(changes)
 	return nil
 }
 
@@ -298,11 +497,26 @@ func (e *Engine) updateContainer(c dockerclient.Container, containers map[string
 			return nil, err
 		}
 		// Convert the ContainerConfig from inspect into our own
-		// cluster.ContainerConfig.
-		container.Config = BuildContainerConfig(*info.Config)
-
-		// FIXME remove "duplicate" lines and move this to cluster/config.go
-		container.Config.CpuShares = container.Config.CpuShares * e.Cpus / 1024.0
+		// cluster.ContainerConfig. Recent daemons only report the resources
+		// in the HostConfig.
+		config := *info.Config
+		config.Labels = make(map[string]string, len(info.Config.Labels))
+		for k, v := range info.Config.Labels {
+			config.Labels[k] = v
+		}
+		if info.HostConfig != nil {
+			config.HostConfig = *info.HostConfig
+		}
+		container.Config = BuildContainerConfig(config)
+
+		// Real CpuShares -> thousandths of a CPU, kept in the label read by
+		// MilliCpus so that fractional requests survive. Containers created
+		// by Swarm already carry their exact request in it.
+		milliCpus := sharesToMilliCpus(container.Config.CpuShares, e.Cpus)
+		if _, ok := container.Config.Labels[SwarmLabelNamespace+".cpus"]; !ok && milliCpus > 0 && container.Config.HostConfig.CpuQuota <= 0 {
+			container.Config.SetMilliCpus(milliCpus)
+		}
+		container.Config.CpuShares = milliCpus / 1000
 		container.Config.HostConfig.CpuShares = container.Config.CpuShares
 
 		// Save the entire inspect back into the container.
@@ -333,19 +547,22 @@ func (e *Engine) refreshLoop() {
 		if err == nil {
 			// Do not check error as older daemon don't support this call
 			e.RefreshVolumes()
+			e.RefreshNetworks()
 			err = e.RefreshImages()
 		}
+		if err == nil && e.IsHealthy() && time.Since(e.specsUpdatedAt) >= specsRefreshPeriod {
+			err = e.refreshSpecs()
+		}
 
 		if err != nil {
-			if e.healthy {
+			if e.setHealthy(false) {
 				e.emitEvent("engine_disconnect")
 			}
-			e.healthy = false
 			log.WithFields(log.Fields{"name": e.Name, "id": e.ID}).Errorf("Flagging engine as dead. Updated state failed: %v", err)
 		} else {
-			if !e.healthy {
+			if !e.IsHealthy() {
 				log.WithFields(log.Fields{"name": e.Name, "id": e.ID}).Info("Engine came back to life. Hooray!")
-				if err := e.updateSpecs(); err != nil {
+				if err := e.refreshSpecs(); err != nil {
 					log.WithFields(log.Fields{"name": e.Name, "id": e.ID}).Errorf("Update engine specs failed: %v", err)
 					continue
 				}
@@ -353,11 +570,19 @@ func (e *Engine) refreshLoop() {
 				e.client.StartMonitorEvents(e.handler, nil)
 				e.emitEvent("engine_reconnect")
 			}
-			e.healthy = true
+			e.setHealthy(true)
+			e.setUpdatedAt()
 		}
 	}
 }
 
+// setUpdatedAt records that the state of the engine was just refreshed.
+func (e *Engine) setUpdatedAt() {
+	e.Lock()
+	e.updatedAt = time.Now()
+	e.Unlock()
+}
+
 func (e *Engine) emitEvent(event string) {
 	// If there is no event handler registered, abort right now.
 	if e.eventHandler == nil {
@@ -374,8 +599,50 @@ func (e *Engine) emitEvent(event string) {
 	e.eventHandler.Handle(ev)
 }
 
+func (e *Engine) emitUpdateEvent(changes []string) {
+	// If there is no event handler registered, abort right now.
+	if e.eventHandler == nil {
+		return
+	}
+	ev := &Event{
+		Event: dockerclient.Event{
+			Status: "engine_update",
+			From:   "swarm",
+			Time:   time.Now().Unix(),
+		},
+		Engine:  e,
+		Changes: changes,
+	}
+	e.eventHandler.Handle(ev)
+}
+
 // UsedMemory returns the sum of memory reserved by containers.
 func (e *Engine) UsedMemory() int64 {
+	var r int64
+	e.RLock()
+	for _, c := range e.containers {
+		memory, _ := e.resourcePolicy.Request(c.Config)
+		r += memory
+	}
+	e.RUnlock()
+	return r
+}
+
+// UsedMilliCpus returns the sum of CPUs reserved by containers, in
+// thousandths of a CPU.
+func (e *Engine) UsedMilliCpus() int64 {
+	var r int64
+	e.RLock()
+	for _, c := range e.containers {
+		_, milliCpus := e.resourcePolicy.Request(c.Config)
+		r += milliCpus
+	}
+	e.RUnlock()
+	return r
+}
+
+// LimitedMemory returns the sum of the memory limits of the containers.
+func (e *Engine) LimitedMemory() int64 {
 	var r int64
 	e.RLock()
 	for _, c := range e.containers {
@@ -385,12 +652,13 @@ func (e *Engine) UsedMemory() int64 {
 	return r
 }
 
-// UsedCpus returns the sum of CPUs reserved by containers.
-func (e *Engine) UsedCpus() int64 {
+// LimitedMilliCpus returns the sum of the CPU limits of the containers, in
+// thousandths of a CPU.
+func (e *Engine) LimitedMilliCpus() int64 {
 	var r int64
 	e.RLock()
 	for _, c := range e.containers {
-		r += c.Config.CpuShares
+		r += c.Config.MilliCpus()
 	}
 	e.RUnlock()
 	return r
@@ -406,6 +674,11 @@ func (e *Engine) TotalCpus() int64 {
 	return e.Cpus + (e.Cpus * e.overcommitRatio / 100)
 }
 
+// TotalMilliCpus returns the total cpus + overcommit, in thousandths of a CPU.
+func (e *Engine) TotalMilliCpus() int64 {
+	return e.Cpus*1000 + (e.Cpus * 1000 * e.overcommitRatio / 100)
+}
+
 // Create a new container
 func (e *Engine) Create(config *ContainerConfig, name string, pullImage bool) (*Container, error) {
 	var (
@@ -419,23 +692,37 @@ func (e *Engine) Create(config *ContainerConfig, name string, pullImage bool) (*
 	// we don't want to mess with the original.
 	dockerConfig := config.ContainerConfig
 
-	// nb of CPUs -> real CpuShares
-
-	// FIXME remove "duplicate" lines and move this to cluster/config.go
-	dockerConfig.CpuShares = int64(math.Ceil(float64(config.CpuShares*1024) / float64(e.Cpus)))
+	// nb of CPUs -> real CpuShares. The request is recorded in a label so
+	// that it survives inspect with its full precision.
+	milliCpus := config.MilliCpus()
+	dockerConfig.CpuShares = milliCpusToShares(milliCpus, e.Cpus)
 	dockerConfig.HostConfig.CpuShares = dockerConfig.CpuShares
+	if milliCpus > 0 {
+		dockerConfig.Labels = make(map[string]string, len(config.Labels)+1)
+		for k, v := range config.Labels {
+			dockerConfig.Labels[k] = v
+		}
+		dockerConfig.Labels[SwarmLabelNamespace+".cpus"] = FormatCpus(milliCpus)
+	}
+
+	// Local networks are named after their engine in the cluster.
+	dockerConfig.HostConfig.NetworkMode = strings.TrimPrefix(dockerConfig.HostConfig.NetworkMode, e.Name+"/")
 
 	if id, err = client.CreateContainer(&dockerConfig, name); err != nil {
 		// If the error is other than not found, abort immediately.
 		if err != dockerclient.ErrNotFound || !pullImage {
 			return nil, err
 		}
-		// Otherwise, try to pull the image...
-		if err = e.Pull(config.Image, nil); err != nil {
+		// Otherwise, try to pull the image. A pinned image is pulled by the
+		// name it was resolved from...
+		if err = e.Pull(config.ImageName(), nil); err != nil {
 			return nil, err
 		}
 		// ...And try again.
 		if id, err = client.CreateContainer(&dockerConfig, name); err != nil {
+			if err == dockerclient.ErrNotFound && config.ImageName() != config.Image {
+				return nil, fmt.Errorf("image %s pulled on %s is not the pinned image %s", config.ImageName(), e.Name, config.Image)
+			}
 			return nil, err
 		}
 	}
@@ -454,6 +741,35 @@ func (e *Engine) Create(config *ContainerConfig, name string, pullImage bool) (*
 	return container, err
 }
 
+// StartContainer starts a container on the engine.
+func (e *Engine) StartContainer(container *Container) error {
+	if err := e.client.StartContainer(container.Id, nil); err != nil {
+		return err
+	}
+
+	// Force a state refresh to pick up the new state of the container.
+	_, err := e.refreshContainer(container.Id, true)
+	return err
+}
+
+// StopContainer stops a container on the engine, killing it after `timeout`
+// seconds.
+func (e *Engine) StopContainer(container *Container, timeout int) error {
+	if err := e.client.StopContainer(container.Id, timeout); err != nil {
+		return err
+	}
+
+	// Force a state refresh to pick up the new state of the container.
+	_, err := e.refreshContainer(container.Id, true)
+	return err
+}
+
+// RefreshContainer inspects a container of the engine again and returns it,
+// or nil if it doesn't exist anymore.
+func (e *Engine) RefreshContainer(ID string) (*Container, error) {
+	return e.refreshContainer(ID, true)
+}
+
 // RemoveContainer a container from the engine.
 func (e *Engine) RemoveContainer(container *Container, force bool) error {
 	if err := e.client.RemoveContainer(container.Id, force, true); err != nil {
@@ -484,6 +800,58 @@ func (e *Engine) Pull(image string, authConfig *dockerclient.AuthConfig) error {
 	return nil
 }
 
+// PullWithProgress pulls an image on the engine, calling progress with every
+// progress message of the daemon.
+func (e *Engine) PullWithProgress(image string, authConfig *dockerclient.AuthConfig, progress func(message *ProgressMessage)) error {
+	if !strings.Contains(image, ":") {
+		image = image + ":latest"
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s://%s/images/create?%s", e.scheme(), e.Addr, url.Values{"fromImage": {image}}.Encode()), nil)
+	if err != nil {
+		return err
+	}
+	if authConfig != nil {
+		buf, err := json.Marshal(authConfig)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(buf))
+	}
+
+	resp, err := e.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return dockerclient.ErrNotFound
+	}
+	if resp.StatusCode >= 400 {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s", strings.TrimSpace(string(data)))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		message := &ProgressMessage{}
+		if err := decoder.Decode(message); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+		progress(message)
+	}
+
+	// force refresh images
+	e.RefreshImages()
+
+	return nil
+}
+
 // Load an image on the engine
 func (e *Engine) Load(reader io.Reader) error {
 	if err := e.client.LoadImage(reader); err != nil {
@@ -496,6 +864,170 @@ func (e *Engine) Load(reader io.Reader) error {
 	return nil
 }
 
+// Save returns a tarball of the images `names` and their parents, as
+// docker save does.
+func (e *Engine) Save(names []string) (io.ReadCloser, error) {
+	resp, err := e.httpClient().Get(fmt.Sprintf("%s://%s/images/get?%s", e.scheme(), e.Addr, url.Values{"names": names}.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unable to save %s on %s: %s", strings.Join(names, ", "), e.Name, strings.TrimSpace(string(data)))
+	}
+	return resp.Body, nil
+}
+
+// CreateVolume creates a volume on the engine.
+func (e *Engine) CreateVolume(request *VolumeCreateRequest) (*Volume, error) {
+	volume := &Volume{Engine: e}
+	if err := e.apiRequest("POST", "/volumes/create", request, &volume.Volume); err != nil {
+		return nil, fmt.Errorf("unable to create volume %s on %s: %v", request.Name, e.Name, err)
+	}
+
+	e.RefreshVolumes()
+	return volume, nil
+}
+
+// RemoveVolume deletes a volume from the engine.
+func (e *Engine) RemoveVolume(name string) error {
+	if err := e.apiRequest("DELETE", "/volumes/"+url.QueryEscape(name), nil, nil); err != nil {
+		return fmt.Errorf("unable to remove volume %s on %s: %v", name, e.Name, err)
+	}
+
+	e.RefreshVolumes()
+	return nil
+}
+
+// RefreshNetworks refreshes the list of networks on the engine. Engines older
+// than 1.9 have no networks API.
+func (e *Engine) RefreshNetworks() error {
+	e.RLock()
+	engineVersion := version.Version(e.Version)
+	e.RUnlock()
+	if engineVersion.LessThan(minNetworksVersion) {
+		return nil
+	}
+
+	networks := []*Network{}
+	if err := e.apiRequest("GET", "/networks", nil, &networks); err != nil {
+		return err
+	}
+	e.Lock()
+	e.networks = networks
+	for _, network := range networks {
+		network.Engine = e
+	}
+	e.Unlock()
+	return nil
+}
+
+// CreateNetwork creates a network on the engine.
+func (e *Engine) CreateNetwork(request *NetworkCreateRequest) (*NetworkCreateResponse, error) {
+	response := &NetworkCreateResponse{}
+	if err := e.apiRequest("POST", "/networks/create", request, response); err != nil {
+		return nil, fmt.Errorf("unable to create network %s on %s: %v", request.Name, e.Name, err)
+	}
+
+	e.RefreshNetworks()
+	return response, nil
+}
+
+// RemoveNetwork deletes a network from the engine.
+func (e *Engine) RemoveNetwork(network *Network) error {
+	if err := e.apiRequest("DELETE", "/networks/"+network.ID, nil, nil); err != nil {
+		return fmt.Errorf("unable to remove network %s on %s: %v", network.Name, e.Name, err)
+	}
+
+	e.RefreshNetworks()
+	return nil
+}
+
+// ConnectNetwork connects a container of the engine to a network.
+func (e *Engine) ConnectNetwork(container *Container, network *Network) error {
+	if err := e.apiRequest("POST", "/networks/"+network.ID+"/connect", map[string]string{"Container": container.Id}, nil); err != nil {
+		return fmt.Errorf("unable to connect %s to network %s on %s: %v", container.Id, network.Name, e.Name, err)
+	}
+
+	e.RefreshNetworks()
+	e.refreshContainer(container.Id, true)
+	return nil
+}
+
+// DisconnectNetwork disconnects a container of the engine from a network.
+func (e *Engine) DisconnectNetwork(container *Container, network *Network) error {
+	if err := e.apiRequest("POST", "/networks/"+network.ID+"/disconnect", map[string]string{"Container": container.Id}, nil); err != nil {
+		return fmt.Errorf("unable to disconnect %s from network %s on %s: %v", container.Id, network.Name, e.Name, err)
+	}
+
+	e.RefreshNetworks()
+	e.refreshContainer(container.Id, true)
+	return nil
+}
+
+// apiRequest sends a request dockerclient doesn't support to the engine. The
+// body `in` and the response `out`, when not nil, are encoded in JSON.
+func (e *Engine) apiRequest(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s://%s%s", e.scheme(), e.Addr, path), body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := e.httpClient()
+	client.Timeout = requestTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Read the body to the end so that the connection is reused.
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return errors.New(strings.TrimSpace(string(data)))
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// httpClient returns a client for the API calls dockerclient doesn't
+// support. All the clients of the engine share the same transport, so that
+// connections to the engine are reused.
+func (e *Engine) httpClient() *http.Client {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.transport == nil {
+		e.transport = &http.Transport{TLSClientConfig: e.tlsConfig}
+	}
+	return &http.Client{Transport: e.transport}
+}
+
+func (e *Engine) scheme() string {
+	if e.tlsConfig != nil {
+		return "https"
+	}
+	return "http"
+}
+
 // Import image
 func (e *Engine) Import(source string, repository string, tag string, imageReader io.Reader) error {
 	if _, err := e.client.ImportImage(source, repository, tag, imageReader); err != nil {
@@ -554,6 +1086,18 @@ func (e *Engine) Volumes() []*Volume {
 	return volumes
 }
 
+// Networks returns all the networks in the engine
+func (e *Engine) Networks() Networks {
+	e.RLock()
+
+	networks := make(Networks, 0, len(e.networks))
+	for _, network := range e.networks {
+		networks = append(networks, network)
+	}
+	e.RUnlock()
+	return networks
+}
+
 // Image returns the image with IDOrName in the engine
 func (e *Engine) Image(IDOrName string) *Image {
 	e.RLock()
@@ -572,6 +1116,11 @@ func (e *Engine) String() string {
 }
 
 func (e *Engine) handler(ev *dockerclient.Event, _ chan error, args ...interface{}) {
+	// Remember the container before the refresh, destroy events drop it.
+	e.RLock()
+	container := e.containers[ev.Id]
+	e.RUnlock()
+
 	// Something changed - refresh our internal state.
 	switch ev.Status {
 	case "pull", "untag", "delete":
@@ -583,20 +1132,32 @@ func (e *Engine) handler(ev *dockerclient.Event, _ chan error, args ...interface
 		// order to update container.Info and get the new NetworkSettings.
 		e.refreshContainer(ev.Id, true)
 		e.RefreshVolumes()
+		// Containers only join and leave networks when they start and die.
+		if ev.Status == "start" || ev.Status == "die" {
+			e.RefreshNetworks()
+		}
 	default:
 		// Otherwise, do a "soft" refresh of the container.
 		e.refreshContainer(ev.Id, false)
 		e.RefreshVolumes()
 	}
 
+	// Containers are only known after their create event.
+	if container == nil {
+		e.RLock()
+		container = e.containers[ev.Id]
+		e.RUnlock()
+	}
+
 	// If there is no event handler registered, abort right now.
 	if e.eventHandler == nil {
 		return
 	}
 
 	event := &Event{
-		Engine: e,
-		Event:  *ev,
+		Engine:    e,
+		Event:     *ev,
+		Container: container,
 	}
 
 	e.eventHandler.Handle(event)
@@ -1,9 +1,15 @@
 package cluster
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/samalba/dockerclient"
@@ -50,6 +56,20 @@ func TestEngineConnectionFailure(t *testing.T) {
 	client.Mock.AssertExpectations(t)
 }
 
+func TestEngineConnectIPv6(t *testing.T) {
+	engine := NewEngine("[::1]:1", 0)
+
+	// Nothing is listening, but the address should have been resolved and
+	// kept in its original form.
+	assert.Error(t, engine.Connect(nil))
+	assert.Equal(t, engine.IP, "::1")
+	assert.Equal(t, engine.Addr, "[::1]:1")
+
+	engine = NewEngine("::1:1", 0)
+	assert.Error(t, engine.Connect(nil))
+	assert.Equal(t, engine.IP, "")
+}
+
 func TestOutdatedEngine(t *testing.T) {
 	engine := NewEngine("test", 0)
 	client := mockclient.NewMockClient()
@@ -80,7 +100,7 @@ func TestEngineCpusMemory(t *testing.T) {
 	assert.True(t, engine.isConnected())
 	assert.True(t, engine.IsHealthy())
 
-	assert.Equal(t, engine.UsedCpus(), int64(0))
+	assert.Equal(t, engine.UsedMilliCpus(), int64(0))
 	assert.Equal(t, engine.UsedMemory(), int64(0))
 
 	client.Mock.AssertExpectations(t)
@@ -178,6 +198,7 @@ func TestCreateContainer(t *testing.T) {
 	mockConfig := config.ContainerConfig
 	mockConfig.CpuShares = int64(math.Ceil(float64(config.CpuShares*1024) / float64(mockInfo.NCPU)))
 	mockConfig.HostConfig.CpuShares = mockConfig.CpuShares
+	mockConfig.Labels = map[string]string{SwarmLabelNamespace + ".cpus": "1"}
 
 	// Everything is ok
 	name := "test1"
@@ -217,6 +238,70 @@ func TestCreateContainer(t *testing.T) {
 	assert.Len(t, engine.Containers(), 2)
 }
 
+func TestCreatePinnedImage(t *testing.T) {
+	engine := NewEngine("test", 0)
+	engine.Name = "test"
+	engine.Cpus = 1
+	client := mockclient.NewMockClient()
+	engine.client = client
+
+	config := BuildContainerConfig(dockerclient.ContainerConfig{Image: "busybox"})
+	config.PinImage("1234567890")
+	mockConfig := config.ContainerConfig
+
+	// The pinned image is pulled by name, but the registry now has another
+	// image under that name.
+	client.On("CreateContainer", &mockConfig, "c1").Return("", dockerclient.ErrNotFound).Twice()
+	client.On("PullImage", "busybox:latest", mock.Anything).Return(nil).Once()
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{}, nil)
+	_, err := engine.Create(config, "c1", true)
+	assert.EqualError(t, err, "image busybox pulled on test is not the pinned image 1234567890")
+	client.Mock.AssertExpectations(t)
+}
+
+func TestPullWithProgress(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/images/create", r.URL.Path)
+		assert.NotEmpty(t, r.Header.Get("X-Registry-Auth"))
+		switch r.URL.Query().Get("fromImage") {
+		case "busybox:latest":
+			io.WriteString(w, `{"status":"Pulling fs layer","progressDetail":{},"id":"8c2e06607696"}`)
+			io.WriteString(w, `{"status":"Downloading","progressDetail":{"current":512,"total":1024},"progress":"[==>  ]","id":"8c2e06607696"}`)
+		default:
+			io.WriteString(w, `{"errorDetail":{"message":"not found"},"error":"not found"}`)
+		}
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	engine := NewEngine(server.Listener.Addr().String(), 0)
+	engine.tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	client := mockclient.NewMockClient()
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{}, nil)
+	engine.client = client
+
+	messages := []*ProgressMessage{}
+	assert.NoError(t, engine.PullWithProgress("busybox", &dockerclient.AuthConfig{Username: "user"}, func(message *ProgressMessage) {
+		messages = append(messages, message)
+	}))
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "8c2e06607696", messages[1].ID)
+	assert.Equal(t, "Downloading", messages[1].Status)
+	assert.Equal(t, `{"current":512,"total":1024}`, string(messages[1].ProgressDetail))
+
+	// The connection to the engine is reused.
+	assert.NoError(t, engine.PullWithProgress("busybox", &dockerclient.AuthConfig{Username: "user"}, func(*ProgressMessage) {}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+
+	assert.EqualError(t, engine.PullWithProgress("missing", &dockerclient.AuthConfig{}, func(*ProgressMessage) {}), "not found")
+}
+
 func TestTotalMemory(t *testing.T) {
 	engine := NewEngine("test", 0.05)
 	engine.Memory = 1024
@@ -237,6 +322,50 @@ func TestTotalCpus(t *testing.T) {
 	assert.Equal(t, engine.TotalCpus(), int64(2))
 }
 
+func TestTotalMilliCpus(t *testing.T) {
+	engine := NewEngine("test", 0.05)
+	engine.Cpus = 2
+	assert.Equal(t, engine.TotalMilliCpus(), int64(2100))
+}
+
+func TestCreateFractionalCpus(t *testing.T) {
+	engine := NewEngine("test", 0)
+	engine.Cpus = 4
+	client := mockclient.NewMockClient()
+	engine.client = client
+
+	config := BuildContainerConfig(dockerclient.ContainerConfig{Image: "busybox", Labels: map[string]string{SwarmLabelNamespace + ".cpus": "0.5"}})
+	mockConfig := config.ContainerConfig
+	mockConfig.CpuShares = 128
+	mockConfig.HostConfig.CpuShares = 128
+	client.On("CreateContainer", &mockConfig, "test").Return("", errors.New("create failed")).Once()
+
+	_, err := engine.Create(config, "test", false)
+	assert.Error(t, err)
+	client.Mock.AssertExpectations(t)
+}
+
+func TestRefreshFractionalCpus(t *testing.T) {
+	info := *mockInfo
+	info.NCPU = 4
+	engine := NewEngine("test", 0)
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(&info, nil)
+	client.On("Version").Return(mockVersion, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{}, nil).Once()
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{{Id: "test"}}, nil).Once()
+	// 0.5 CPU out of 4 is 128 shares.
+	client.On("InspectContainer", "test").Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{CpuShares: 128}}, nil).Once()
+	assert.NoError(t, engine.ConnectWithClient(client))
+
+	containers := engine.Containers()
+	assert.Len(t, containers, 1)
+	assert.Equal(t, int64(500), containers[0].Config.MilliCpus())
+	assert.Equal(t, int64(500), engine.UsedMilliCpus())
+}
+
 func TestUsedCpus(t *testing.T) {
 	var (
 		containerNcpu = []int64{1, 2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37, 41, 43, 47}
@@ -261,7 +390,7 @@ func TestUsedCpus(t *testing.T) {
 				client.On("InspectContainer", "test").Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{CpuShares: cpuShares}}, nil).Once()
 				engine.ConnectWithClient(client)
 
-				assert.Equal(t, engine.UsedCpus(), cn)
+				assert.Equal(t, engine.UsedMilliCpus(), cn*1000)
 			}
 		}
 	}
@@ -300,3 +429,153 @@ func TestContainerRemovedDuringRefresh(t *testing.T) {
 
 	client.Mock.AssertExpectations(t)
 }
+
+type recordingHandler struct {
+	events []*Event
+}
+
+func (h *recordingHandler) Handle(e *Event) error {
+	h.events = append(h.events, e)
+	return nil
+}
+
+func TestEngineRefreshSpecs(t *testing.T) {
+	info := *mockInfo
+	info.NCPU = 2
+	updated := info
+	updated.NCPU = 4
+	updated.Labels = []string{"foo=baz", "ssd=true"}
+
+	engine := NewEngine("test", 0)
+	handler := &recordingHandler{}
+	assert.NoError(t, engine.RegisterEventHandler(handler))
+
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(&info, nil).Once()
+	client.On("Version").Return(mockVersion, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{}, nil)
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	assert.NoError(t, engine.ConnectWithClient(client))
+	assert.Equal(t, engine.Labels["foo"], "bar")
+
+	// Nothing changed: no event.
+	client.On("Info").Return(&info, nil).Once()
+	handler.events = nil
+	assert.NoError(t, engine.refreshSpecs())
+	assert.Empty(t, handler.events)
+
+	// New labels and resources are picked up and reported.
+	client.On("Info").Return(&updated, nil).Once()
+	assert.NoError(t, engine.refreshSpecs())
+	assert.Equal(t, engine.Cpus, int64(4))
+	assert.Equal(t, engine.Labels["foo"], "baz")
+	assert.Equal(t, engine.Labels["ssd"], "true")
+	if assert.Len(t, handler.events, 1) {
+		assert.Equal(t, handler.events[0].Status, "engine_update")
+		assert.Equal(t, handler.events[0].Changes, []string{
+			"cpus: 2 -> 4",
+			"label foo: bar -> baz",
+			"label ssd: added true",
+		})
+	}
+
+	client.Mock.AssertExpectations(t)
+}
+
+func TestEngineRefreshSpecsIDChanged(t *testing.T) {
+	info := *mockInfo
+	reinstalled := info
+	reinstalled.ID = "new-id"
+
+	engine := NewEngine("test", 0)
+	handler := &recordingHandler{}
+	assert.NoError(t, engine.RegisterEventHandler(handler))
+
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(&info, nil).Once()
+	client.On("Version").Return(mockVersion, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{{Id: "old"}}, nil).Once()
+	client.On("InspectContainer", "old").Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{}}, nil).Once()
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{}, nil)
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	assert.NoError(t, engine.ConnectWithClient(client))
+	assert.Len(t, engine.Containers(), 1)
+
+	// Docker got reinstalled: the engine reports a new ID and new containers.
+	client.On("Info").Return(&reinstalled, nil).Once()
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{{Id: "new"}}, nil).Once()
+	client.On("InspectContainer", "new").Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{}}, nil).Once()
+	assert.NoError(t, engine.refreshSpecs())
+	assert.Equal(t, engine.ID, "new-id")
+	containers := engine.Containers()
+	if assert.Len(t, containers, 1) {
+		assert.Equal(t, containers[0].Id, "new")
+	}
+	if assert.Len(t, handler.events, 2) {
+		assert.Equal(t, handler.events[1].Status, "engine_update")
+		assert.Equal(t, handler.events[1].Changes, []string{"id: id -> new-id"})
+	}
+
+	client.Mock.AssertExpectations(t)
+}
+
+func TestEngineNodeLabels(t *testing.T) {
+	engine := NewEngine("test", 0)
+	engine.SetNodeLabels(map[string]string{"zone": "eu"})
+	assert.Equal(t, "eu", engine.Labels[NodeLabelPrefix+"zone"])
+	assert.Equal(t, map[string]string{"zone": "eu"}, engine.NodeLabels())
+
+	// Manager labels survive specs updates and daemon labels can't use the
+	// manager prefix.
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(&dockerclient.Info{ID: "id", Name: "name", NCPU: 1, MemTotal: 1, Labels: []string{NodeLabelPrefix + "zone=us", "foo=bar"}}, nil)
+	client.On("Version").Return(mockVersion, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{}, nil)
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	assert.NoError(t, engine.ConnectWithClient(client))
+
+	assert.Equal(t, "eu", engine.Labels[NodeLabelPrefix+"zone"])
+	assert.Equal(t, "bar", engine.Labels["foo"])
+
+	engine.SetNodeLabels(nil)
+	_, exists := engine.Labels[NodeLabelPrefix+"zone"]
+	assert.False(t, exists)
+	assert.Equal(t, "bar", engine.Labels["foo"])
+}
+
+func TestSystemReserved(t *testing.T) {
+	engine := NewEngine("test", 0)
+	engine.Memory = 8 * 1024 * 1024 * 1024
+	engine.Cpus = 4
+
+	assert.Equal(t, engine.TotalMemory(), engine.AllocatableMemory())
+	assert.Equal(t, int64(4000), engine.AllocatableMilliCpus())
+
+	// Global default.
+	engine.SetSystemReserved(1024*1024*1024, 500)
+	assert.Equal(t, int64(7*1024*1024*1024), engine.AllocatableMemory())
+	assert.Equal(t, int64(3500), engine.AllocatableMilliCpus())
+
+	// Daemon labels override the default.
+	engine.Labels = map[string]string{reservedMemoryLabel: "2g", reservedCpusLabel: "1.5"}
+	assert.Equal(t, int64(6*1024*1024*1024), engine.AllocatableMemory())
+	assert.Equal(t, int64(2500), engine.AllocatableMilliCpus())
+
+	// Manager labels override the daemon labels.
+	engine.SetNodeLabels(map[string]string{reservedCpusNodeLabel: "3.25"})
+	assert.Equal(t, int64(750), engine.AllocatableMilliCpus())
+
+	// Invalid values are ignored.
+	engine.SetNodeLabels(map[string]string{reservedCpusNodeLabel: "lots", reservedMemoryNodeLabel: "lots"})
+	assert.Equal(t, int64(2500), engine.AllocatableMilliCpus())
+	assert.Equal(t, int64(6*1024*1024*1024), engine.AllocatableMemory())
+
+	// The reservation can't make the allocatable resources negative.
+	engine.SetNodeLabels(map[string]string{reservedCpusNodeLabel: "8"})
+	assert.Equal(t, int64(0), engine.AllocatableMilliCpus())
+}
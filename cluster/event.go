@@ -6,6 +6,14 @@ import "github.com/samalba/dockerclient"
 type Event struct {
 	dockerclient.Event
 	Engine *Engine
+
+	// Container is the container the event refers to, if any. It is looked
+	// up before the engine state is refreshed so that it is still set for
+	// destroy events.
+	Container *Container
+
+	// Changes lists what was updated on the engine for engine_update events.
+	Changes []string
 }
 
 // EventHandler is exported
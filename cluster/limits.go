@@ -0,0 +1,245 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/units"
+)
+
+// ResourceLimits are the resources requested by default by the containers
+// which don't request any, and the bounds of the resources containers can
+// request. Zero values mean no default and no bound. CPUs are in thousandths
+// of a CPU.
+type ResourceLimits struct {
+	DefaultMemory    int64
+	DefaultMilliCpus int64
+	MinMemory        int64
+	MaxMemory        int64
+	MinMilliCpus     int64
+	MaxMilliCpus     int64
+}
+
+// ResourcePolicy are the resource limits of the cluster, optionally
+// overridden per tenant. Tenants are identified by the value of a container
+// label.
+type ResourcePolicy struct {
+	ResourceLimits
+
+	// ApplyDefaults writes the default requests into the config of the
+	// containers, which makes them runtime limits, rather than only using
+	// them for scheduling.
+	ApplyDefaults bool
+
+	TenantLabel string
+	Tenants     map[string]ResourceLimits
+}
+
+// resourceLimitOptions are the options of a ResourceLimits, relative to
+// swarm. or to swarm.tenant.<tenant>.
+var resourceLimitOptions = []string{"default.memory", "default.cpus", "min.memory", "max.memory", "min.cpus", "max.cpus"}
+
+// ParseResourcePolicy reads the resource policy from the cluster options:
+//
+//	swarm.default.memory, swarm.default.cpus    default requests
+//	swarm.min.memory, swarm.max.memory          bounds of the memory requests
+//	swarm.min.cpus, swarm.max.cpus              bounds of the CPU requests
+//	swarm.default.apply=true                    write the defaults into the container config
+//	swarm.tenant.label=<label>                  label identifying the tenant of a container
+//	swarm.tenant.<tenant>.<option>              per tenant override of the options above
+//
+// It returns nil if no option is set.
+func ParseResourcePolicy(options DriverOpts) (*ResourcePolicy, error) {
+	policy := &ResourcePolicy{Tenants: make(map[string]ResourceLimits)}
+	set := false
+
+	for _, option := range resourceLimitOptions {
+		if value, ok := options.String("swarm."+option, ""); ok {
+			if err := policy.ResourceLimits.set(option, value); err != nil {
+				return nil, err
+			}
+			set = true
+		}
+	}
+
+	if value, ok := options.String("swarm.default.apply", ""); ok {
+		apply, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for swarm.default.apply", value)
+		}
+		policy.ApplyDefaults = apply
+		set = true
+	}
+
+	policy.TenantLabel, _ = options.String("swarm.tenant.label", "")
+	for _, opt := range options {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], "swarm.tenant.") || kv[0] == "swarm.tenant.label" {
+			continue
+		}
+		if policy.TenantLabel == "" {
+			return nil, fmt.Errorf("%s requires swarm.tenant.label", kv[0])
+		}
+
+		key := strings.TrimPrefix(kv[0], "swarm.tenant.")
+		found := false
+		for _, option := range resourceLimitOptions {
+			if !strings.HasSuffix(key, "."+option) {
+				continue
+			}
+			tenant := strings.TrimSuffix(key, "."+option)
+			limits, ok := policy.Tenants[tenant]
+			if !ok {
+				limits = policy.ResourceLimits
+			}
+			if err := limits.set(option, kv[1]); err != nil {
+				return nil, err
+			}
+			policy.Tenants[tenant] = limits
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown option %s", kv[0])
+		}
+		set = true
+	}
+
+	if !set {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+func (l *ResourceLimits) set(option, value string) error {
+	var (
+		v   int64
+		err error
+	)
+	if strings.HasSuffix(option, ".memory") {
+		v, err = units.RAMInBytes(value)
+	} else {
+		v, err = ParseCpus(value)
+	}
+	if err != nil || v < 0 {
+		return fmt.Errorf("invalid value %q for %s", value, option)
+	}
+
+	switch option {
+	case "default.memory":
+		l.DefaultMemory = v
+	case "default.cpus":
+		l.DefaultMilliCpus = v
+	case "min.memory":
+		l.MinMemory = v
+	case "max.memory":
+		l.MaxMemory = v
+	case "min.cpus":
+		l.MinMilliCpus = v
+	case "max.cpus":
+		l.MaxMilliCpus = v
+	}
+	return nil
+}
+
+// limits returns the limits of the tenant of the container, if any.
+func (p *ResourcePolicy) limits(config *ContainerConfig) ResourceLimits {
+	if p.TenantLabel != "" {
+		if limits, ok := p.Tenants[config.Labels[p.TenantLabel]]; ok {
+			return limits
+		}
+	}
+	return p.ResourceLimits
+}
+
+// Check verifies that the resources requested by the container are within
+// the bounds of the policy.
+func (p *ResourcePolicy) Check(config *ContainerConfig) error {
+	if p == nil {
+		return nil
+	}
+	limits := p.limits(config)
+
+	if config.Memory > 0 {
+		if limits.MaxMemory > 0 && config.Memory > limits.MaxMemory {
+			return fmt.Errorf("memory limit %s exceeds the maximum of %s", units.BytesSize(float64(config.Memory)), units.BytesSize(float64(limits.MaxMemory)))
+		}
+		if config.Memory < limits.MinMemory {
+			return fmt.Errorf("memory limit %s is below the minimum of %s", units.BytesSize(float64(config.Memory)), units.BytesSize(float64(limits.MinMemory)))
+		}
+	}
+	if milliCpus := config.MilliCpus(); milliCpus > 0 {
+		if limits.MaxMilliCpus > 0 && milliCpus > limits.MaxMilliCpus {
+			return fmt.Errorf("CPU limit %s exceeds the maximum of %s", FormatCpus(milliCpus), FormatCpus(limits.MaxMilliCpus))
+		}
+		if milliCpus < limits.MinMilliCpus {
+			return fmt.Errorf("CPU limit %s is below the minimum of %s", FormatCpus(milliCpus), FormatCpus(limits.MinMilliCpus))
+		}
+	}
+	return nil
+}
+
+// Apply writes the default requests into the config of the container if the
+// policy says so and the container doesn't request any.
+func (p *ResourcePolicy) Apply(config *ContainerConfig) {
+	if p == nil || !p.ApplyDefaults {
+		return
+	}
+	limits := p.limits(config)
+
+	if config.Memory == 0 && limits.DefaultMemory > 0 {
+		config.Memory = limits.DefaultMemory
+		config.HostConfig.Memory = limits.DefaultMemory
+	}
+	if config.MilliCpus() == 0 && limits.DefaultMilliCpus > 0 {
+		config.SetMilliCpus(limits.DefaultMilliCpus)
+	}
+}
+
+// Request returns the resources requested by the container: its reservations
+// or limits or, if it has none, the default requests. CPUs are in thousandths
+// of a CPU.
+func (p *ResourcePolicy) Request(config *ContainerConfig) (memory, milliCpus int64) {
+	memory, milliCpus = config.ReservedMemory(), config.ReservedMilliCpus()
+	if p == nil {
+		return memory, milliCpus
+	}
+	limits := p.limits(config)
+
+	if memory == 0 {
+		memory = limits.DefaultMemory
+	}
+	if milliCpus == 0 {
+		milliCpus = limits.DefaultMilliCpus
+	}
+	return memory, milliCpus
+}
+
+// WithRequests returns a copy of the config of the container requesting the
+// resources returned by Request, to schedule it.
+func (p *ResourcePolicy) WithRequests(config *ContainerConfig) *ContainerConfig {
+	if p == nil {
+		return config
+	}
+	memory, milliCpus := p.Request(config)
+
+	requested := *config
+	requested.Labels = make(map[string]string, len(config.Labels)+1)
+	for k, v := range config.Labels {
+		requested.Labels[k] = v
+	}
+	requested.Memory = memory
+	if milliCpus > 0 {
+		requested.SetMilliCpus(milliCpus)
+	}
+	return &requested
+}
+
+// SetResourcePolicy sets the policy used to account for the resources of the
+// containers of the engine which don't request any.
+func (e *Engine) SetResourcePolicy(policy *ResourcePolicy) {
+	e.Lock()
+	e.resourcePolicy = policy
+	e.Unlock()
+}
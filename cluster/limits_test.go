@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseResourcePolicy(t *testing.T) {
+	policy, err := ParseResourcePolicy(DriverOpts{"swarm.overcommit=0.1"})
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+
+	policy, err = ParseResourcePolicy(DriverOpts{
+		"swarm.default.memory=256m",
+		"swarm.default.cpus=1",
+		"swarm.max.memory=2g",
+		"swarm.default.apply=true",
+		"swarm.tenant.label=com.example.tenant",
+		"swarm.tenant.team.a.max.memory=4g",
+		"swarm.tenant.team.a.min.cpus=2",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ResourceLimits{DefaultMemory: 256 * 1024 * 1024, DefaultMilliCpus: 1000, MaxMemory: 2 * 1024 * 1024 * 1024}, policy.ResourceLimits)
+	assert.True(t, policy.ApplyDefaults)
+	assert.Equal(t, "com.example.tenant", policy.TenantLabel)
+	// Tenants inherit the options they don't override.
+	assert.Equal(t, map[string]ResourceLimits{
+		"team.a": {DefaultMemory: 256 * 1024 * 1024, DefaultMilliCpus: 1000, MaxMemory: 4 * 1024 * 1024 * 1024, MinMilliCpus: 2000},
+	}, policy.Tenants)
+
+	_, err = ParseResourcePolicy(DriverOpts{"swarm.max.memory=lots"})
+	assert.Error(t, err)
+	_, err = ParseResourcePolicy(DriverOpts{"swarm.default.apply=maybe"})
+	assert.Error(t, err)
+	_, err = ParseResourcePolicy(DriverOpts{"swarm.tenant.a.max.memory=1g"})
+	assert.Error(t, err)
+	_, err = ParseResourcePolicy(DriverOpts{"swarm.tenant.label=tenant", "swarm.tenant.a.unknown=1"})
+	assert.Error(t, err)
+}
+
+func TestResourcePolicy(t *testing.T) {
+	policy := &ResourcePolicy{
+		ResourceLimits: ResourceLimits{DefaultMemory: 256, DefaultMilliCpus: 1000, MinMemory: 128, MaxMemory: 1024, MaxMilliCpus: 2000},
+		TenantLabel:    "tenant",
+		Tenants: map[string]ResourceLimits{
+			"big": {DefaultMemory: 1024, MaxMemory: 4096},
+		},
+	}
+
+	config := BuildContainerConfig(dockerclient.ContainerConfig{})
+	assert.NoError(t, policy.Check(config))
+	memory, cpus := policy.Request(config)
+	assert.Equal(t, int64(256), memory)
+	assert.Equal(t, int64(1000), cpus)
+	requested := policy.WithRequests(config)
+	assert.Equal(t, int64(256), requested.Memory)
+	assert.Equal(t, int64(0), config.Memory)
+
+	// Defaults are only written into the config when asked to.
+	policy.Apply(config)
+	assert.Equal(t, int64(0), config.Memory)
+	policy.ApplyDefaults = true
+	policy.Apply(config)
+	assert.Equal(t, int64(256), config.Memory)
+	assert.Equal(t, int64(256), config.HostConfig.Memory)
+	assert.Equal(t, int64(1000), config.MilliCpus())
+
+	assert.Error(t, policy.Check(BuildContainerConfig(dockerclient.ContainerConfig{Memory: 2048})))
+	assert.Error(t, policy.Check(BuildContainerConfig(dockerclient.ContainerConfig{Memory: 64})))
+	assert.Error(t, policy.Check(BuildContainerConfig(dockerclient.ContainerConfig{CpuShares: 4})))
+
+	// Tenants have their own limits.
+	config = BuildContainerConfig(dockerclient.ContainerConfig{Memory: 2048, Labels: map[string]string{"tenant": "big"}})
+	assert.NoError(t, policy.Check(config))
+	memory, _ = policy.Request(BuildContainerConfig(dockerclient.ContainerConfig{Labels: map[string]string{"tenant": "big"}}))
+	assert.Equal(t, int64(1024), memory)
+
+	// Without a policy, containers request what they ask for.
+	var none *ResourcePolicy
+	assert.NoError(t, none.Check(config))
+	memory, _ = none.Request(config)
+	assert.Equal(t, int64(2048), memory)
+	assert.Equal(t, config, none.WithRequests(config))
+}
+
+func TestUsedResourcesWithPolicy(t *testing.T) {
+	engine := NewEngine("test", 0)
+	engine.AddContainer(&Container{Container: dockerclient.Container{Id: "limited"}, Config: BuildContainerConfig(dockerclient.ContainerConfig{Memory: 512, CpuShares: 2})})
+	engine.AddContainer(&Container{Container: dockerclient.Container{Id: "unlimited"}, Config: BuildContainerConfig(dockerclient.ContainerConfig{})})
+	assert.Equal(t, int64(512), engine.UsedMemory())
+	assert.Equal(t, int64(2000), engine.UsedMilliCpus())
+
+	engine.SetResourcePolicy(&ResourcePolicy{ResourceLimits: ResourceLimits{DefaultMemory: 256, DefaultMilliCpus: 1000}})
+	assert.Equal(t, int64(768), engine.UsedMemory())
+	assert.Equal(t, int64(3000), engine.UsedMilliCpus())
+}
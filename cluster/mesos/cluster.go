@@ -29,7 +29,8 @@ type Cluster struct {
 
 	driver              *mesosscheduler.MesosSchedulerDriver
 	dockerEnginePort    string
-	eventHandler        cluster.EventHandler
+	eventHandlers       []cluster.EventHandler
+	eventHandlersLock   sync.RWMutex
 	master              string
 	slaves              map[string]*slave
 	scheduler           *scheduler.Scheduler
@@ -136,27 +137,31 @@ func NewCluster(scheduler *scheduler.Scheduler, TLSConfig *tls.Config, master st
 
 // Handle callbacks for the events
 func (c *Cluster) Handle(e *cluster.Event) error {
-	if c.eventHandler == nil {
-		return nil
-	}
-	if err := c.eventHandler.Handle(e); err != nil {
-		log.Error(err)
+	// Fan out the event to every handler.
+	c.eventHandlersLock.RLock()
+	handlers := c.eventHandlers
+	c.eventHandlersLock.RUnlock()
+
+	for _, h := range handlers {
+		if err := h.Handle(e); err != nil {
+			log.Error(err)
+		}
 	}
 	return nil
 }
 
-// RegisterEventHandler registers an event handler.
+// RegisterEventHandler registers an event handler. Every event is sent to
+// all the registered handlers.
 func (c *Cluster) RegisterEventHandler(h cluster.EventHandler) error {
-	if c.eventHandler != nil {
-		return errors.New("event handler already set")
-	}
-	c.eventHandler = h
+	c.eventHandlersLock.Lock()
+	c.eventHandlers = append(c.eventHandlers, h)
+	c.eventHandlersLock.Unlock()
 	return nil
 }
 
 // CreateContainer for container creation in Mesos task
 func (c *Cluster) CreateContainer(config *cluster.ContainerConfig, name string) (*cluster.Container, error) {
-	if config.Memory == 0 && config.CpuShares == 0 {
+	if config.Memory == 0 && config.MilliCpus() == 0 {
 		return nil, errResourcesNeeded
 	}
 
@@ -270,7 +275,7 @@ func (c *Cluster) RemoveImage(image *cluster.Image) ([]*dockerclient.ImageDelete
 }
 
 // Pull will pull images on the cluster nodes
-func (c *Cluster) Pull(name string, authConfig *dockerclient.AuthConfig, callback func(where, status string, err error)) {
+func (c *Cluster) Pull(name string, authConfig *dockerclient.AuthConfig, options *cluster.PullOptions, callback func(where string, message *cluster.ProgressMessage, err error)) {
 
 }
 
@@ -302,6 +307,31 @@ func (c *Cluster) Volume(name string) *cluster.Volume {
 	return nil
 }
 
+// CreateVolume is not supported with mesos.
+func (c *Cluster) CreateVolume(request *cluster.VolumeCreateRequest) (*cluster.Volume, error) {
+	return nil, errNotSupported
+}
+
+// RemoveVolume is not supported with mesos.
+func (c *Cluster) RemoveVolume(volume *cluster.Volume) error {
+	return errNotSupported
+}
+
+// Networks returns all the networks in the cluster.
+func (c *Cluster) Networks() cluster.Networks {
+	return cluster.Networks{}
+}
+
+// CreateNetwork is not supported with mesos.
+func (c *Cluster) CreateNetwork(request *cluster.NetworkCreateRequest) (*cluster.NetworkCreateResponse, error) {
+	return nil, errNotSupported
+}
+
+// RemoveNetwork is not supported with mesos.
+func (c *Cluster) RemoveNetwork(network *cluster.Network) error {
+	return errNotSupported
+}
+
 // listNodes returns all the nodess in the cluster.
 func (c *Cluster) listNodes() []*node.Node {
 	c.RLock()
@@ -311,8 +341,8 @@ func (c *Cluster) listNodes() []*node.Node {
 	for _, s := range c.slaves {
 		n := node.NewNode(s.engine)
 		n.ID = s.id
-		n.TotalCpus = int64(sumScalarResourceValue(s.offers, "cpus"))
-		n.UsedCpus = 0
+		n.TotalMilliCpus = int64(sumScalarResourceValue(s.offers, "cpus") * 1000)
+		n.UsedMilliCpus = 0
 		n.TotalMemory = int64(sumScalarResourceValue(s.offers, "mem")) * 1024 * 1024
 		n.UsedMemory = 0
 		out = append(out, n)
@@ -320,6 +350,76 @@ func (c *Cluster) listNodes() []*node.Node {
 	return out
 }
 
+// Nodes returns the description of every slave of the cluster, sorted by
+// name. The resources are the ones currently offered by the slave.
+func (c *Cluster) Nodes() []*cluster.NodeInfo {
+	c.RLock()
+	defer c.RUnlock()
+
+	out := []*cluster.NodeInfo{}
+	for _, s := range c.slaves {
+		out = append(out, c.nodeInfo(s))
+	}
+	sort.Sort(cluster.NodeInfoSorter(out))
+	return out
+}
+
+// Node returns the slave matching IDOrName, either by ID or by name.
+func (c *Cluster) Node(IDOrName string) *cluster.NodeInfo {
+	c.RLock()
+	defer c.RUnlock()
+
+	if s, ok := c.slaves[IDOrName]; ok {
+		return c.nodeInfo(s)
+	}
+	for _, s := range c.slaves {
+		if s.engine.Name == IDOrName {
+			return c.nodeInfo(s)
+		}
+	}
+	return nil
+}
+
+// UpdateNodeLabels is not supported with mesos.
+func (c *Cluster) UpdateNodeLabels(IDOrName string, labels map[string]string, removed []string) error {
+	return errNotSupported
+}
+
+// DistributeImage is not supported with mesos.
+func (c *Cluster) DistributeImage(name string, constraints []string, concurrency int, callback func(where, status string, err error)) error {
+	return errNotSupported
+}
+
+// RestartStatus returns nil, restart policies are not supported with mesos.
+func (c *Cluster) RestartStatus(container *cluster.Container) *cluster.RestartStatus {
+	return nil
+}
+
+// MoveContainer is not supported with mesos.
+func (c *Cluster) MoveContainer(container *cluster.Container, nodeIDOrName string) (*cluster.Container, error) {
+	return nil, errNotSupported
+}
+
+// UpdateContainers is not supported with mesos.
+func (c *Cluster) UpdateContainers(containers []*cluster.Container, options *cluster.UpdateOptions, callback func(where, status string, err error)) error {
+	return errNotSupported
+}
+
+// PruneImages is not supported with mesos.
+func (c *Cluster) PruneImages(policy *cluster.ImagePrunePolicy) ([]*cluster.ImagePruneReport, error) {
+	return nil, errNotSupported
+}
+
+func (c *Cluster) nodeInfo(s *slave) *cluster.NodeInfo {
+	info := cluster.NewNodeInfo(s.engine)
+	info.ID = s.id
+	info.Resources.Cpus = sumScalarResourceValue(s.offers, "cpus")
+	info.Resources.Memory = int64(sumScalarResourceValue(s.offers, "mem")) * 1024 * 1024
+	info.Resources.AllocatableCpus = info.Resources.Cpus
+	info.Resources.AllocatableMemory = info.Resources.Memory
+	return info
+}
+
 func (c *Cluster) listOffers() []*mesosproto.Offer {
 	c.RLock()
 	defer c.RUnlock()
@@ -522,15 +622,16 @@ func (c *Cluster) RANDOMENGINE() (*cluster.Engine, error) {
 	return nil, nil
 }
 
-// BuildImage build an image
-func (c *Cluster) BuildImage(buildImage *dockerclient.BuildImage, out io.Writer) error {
+// BuildImage build an image. The image built can't be distributed with mesos.
+func (c *Cluster) BuildImage(buildImage *dockerclient.BuildImage, options *cluster.BuildOptions, out io.Writer) error {
+	if options != nil && options.Distribute {
+		return errNotSupported
+	}
+
 	c.scheduler.Lock()
 
 	// get an engine
-	config := &cluster.ContainerConfig{dockerclient.ContainerConfig{
-		CpuShares: buildImage.CpuShares,
-		Memory:    buildImage.Memory,
-	}}
+	config := cluster.BuildConfig(buildImage, options)
 	n, err := c.scheduler.SelectNodeForContainer(c.listNodes(), config)
 	c.scheduler.Unlock()
 	if err != nil {
@@ -2,6 +2,7 @@ package mesos
 
 import (
 	"fmt"
+	"net"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
@@ -43,7 +44,7 @@ func (c *Cluster) ResourceOffers(_ mesosscheduler.SchedulerDriver, offers []*mes
 		}
 		s, ok := c.slaves[slaveID]
 		if !ok {
-			engine := cluster.NewEngine(*offer.Hostname+":"+dockerPort, 0)
+			engine := cluster.NewEngine(net.JoinHostPort(*offer.Hostname, dockerPort), 0)
 			if err := engine.Connect(c.TLSConfig); err != nil {
 				log.Error(err)
 			} else {
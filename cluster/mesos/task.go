@@ -109,8 +109,8 @@ func (t *task) build(slaveID string, offers map[string]*mesosproto.Offer) {
 		t.Container.Docker.Network = mesosproto.ContainerInfo_DockerInfo_BRIDGE.Enum()
 	}
 
-	if cpus := t.config.CpuShares; cpus > 0 {
-		t.Resources = append(t.Resources, mesosutil.NewScalarResource("cpus", float64(cpus)))
+	if milliCpus := t.config.MilliCpus(); milliCpus > 0 {
+		t.Resources = append(t.Resources, mesosutil.NewScalarResource("cpus", float64(milliCpus)/1000))
 	}
 
 	if mem := t.config.Memory; mem > 0 {
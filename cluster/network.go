@@ -0,0 +1,126 @@
+package cluster
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Network is a network of an engine, as described by the networks API of the
+// engine. The fields swarm doesn't use are passed through as they are.
+type Network struct {
+	Name       string
+	ID         string `json:"Id"`
+	Scope      string
+	Driver     string
+	IPAM       json.RawMessage            `json:",omitempty"`
+	Containers map[string]json.RawMessage `json:",omitempty"`
+	Options    map[string]string          `json:",omitempty"`
+
+	Engine *Engine `json:"-"`
+}
+
+// IsLocal returns true if the network only exists on its engine, false for
+// the networks of multi-host drivers, which have the same ID on every engine.
+func (n *Network) IsLocal() bool {
+	return n.Scope != "global"
+}
+
+// NetworkCreateRequest is the body of a network creation.
+type NetworkCreateRequest struct {
+	Name           string
+	CheckDuplicate bool
+	Driver         string
+	IPAM           json.RawMessage   `json:",omitempty"`
+	Options        map[string]string `json:",omitempty"`
+}
+
+// NetworkCreateResponse is the response of a network creation.
+type NetworkCreateResponse struct {
+	ID      string `json:"Id"`
+	Warning string
+}
+
+// Networks represents a list of networks
+type Networks []*Network
+
+// Uniq returns the networks as the clients see them: global networks once,
+// with the containers of every engine, and local networks with the name of
+// their engine prepended to their name.
+func (networks Networks) Uniq() Networks {
+	out := Networks{}
+	global := make(map[string]*Network)
+	for _, network := range networks {
+		if network.IsLocal() {
+			local := *network
+			local.Name = network.Engine.Name + "/" + network.Name
+			out = append(out, &local)
+			continue
+		}
+
+		if merged, ok := global[network.ID]; ok {
+			for id, endpoint := range network.Containers {
+				merged.Containers[id] = endpoint
+			}
+			continue
+		}
+		merged := *network
+		merged.Containers = make(map[string]json.RawMessage, len(network.Containers))
+		for id, endpoint := range network.Containers {
+			merged.Containers[id] = endpoint
+		}
+		global[network.ID] = &merged
+		out = append(out, &merged)
+	}
+	return out
+}
+
+// Get returns a network using its ID, its name or a prefix of its ID. Names
+// can be prefixed with the name of the engine (ex. node-1/bridge), and are
+// ambiguous, and match nothing, when several engines have a local network
+// with this name.
+func (networks Networks) Get(IDOrName string) *Network {
+	// Abort immediately if the name is empty.
+	if len(IDOrName) == 0 {
+		return nil
+	}
+
+	// Match exact ID.
+	for _, network := range networks {
+		if network.ID == IDOrName {
+			return network
+		}
+	}
+
+	// Match name or engine/name.
+	candidates := []*Network{}
+	for _, network := range networks {
+		if network.Name == IDOrName || network.Engine != nil && network.Engine.Name+"/"+network.Name == IDOrName {
+			candidates = append(candidates, network)
+		}
+	}
+	if len(candidates) > 0 {
+		return sameNetwork(candidates)
+	}
+
+	// Match ID prefix.
+	for _, network := range networks {
+		if strings.HasPrefix(network.ID, IDOrName) {
+			candidates = append(candidates, network)
+		}
+	}
+	return sameNetwork(candidates)
+}
+
+// sameNetwork returns the first candidate when they all are the same network,
+// a global network seen from several engines, and nil otherwise.
+func sameNetwork(candidates []*Network) *Network {
+	if len(candidates) == 0 {
+		return nil
+	}
+	for _, network := range candidates {
+		if network.ID != candidates[0].ID {
+			return nil
+		}
+	}
+	return candidates[0]
+}
@@ -0,0 +1,155 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testNetworks() Networks {
+	node1 := &Engine{ID: "id-1", Name: "node-1"}
+	node2 := &Engine{ID: "id-2", Name: "node-2"}
+	return Networks{
+		{ID: "bridge1", Name: "bridge", Scope: "local", Engine: node1},
+		{ID: "bridge2", Name: "bridge", Scope: "local", Engine: node2},
+		{ID: "overlay", Name: "backend", Scope: "global", Engine: node1, Containers: map[string]json.RawMessage{"c1": json.RawMessage(`{}`)}},
+		{ID: "overlay", Name: "backend", Scope: "global", Engine: node2, Containers: map[string]json.RawMessage{"c2": json.RawMessage(`{}`)}},
+		{ID: "private", Name: "private", Scope: "local", Engine: node2},
+	}
+}
+
+func TestNetworksGet(t *testing.T) {
+	networks := testNetworks()
+
+	assert.Equal(t, networks.Get("bridge2"), networks[1])
+	assert.Equal(t, networks.Get("node-1/bridge"), networks[0])
+	assert.Equal(t, networks.Get("private"), networks[4])
+	assert.Equal(t, networks.Get("node-2/private"), networks[4])
+	assert.Equal(t, networks.Get("priv"), networks[4])
+
+	// Global networks are the same on every node.
+	assert.Equal(t, networks.Get("backend").ID, "overlay")
+	assert.Equal(t, networks.Get("over").ID, "overlay")
+
+	// Ambiguous names and prefixes.
+	assert.Nil(t, networks.Get("bridge"))
+	assert.Nil(t, networks.Get("br"))
+	assert.Nil(t, networks.Get("node-1/private"))
+	assert.Nil(t, networks.Get(""))
+}
+
+func TestNetworksUniq(t *testing.T) {
+	networks := testNetworks().Uniq()
+	assert.Len(t, networks, 4)
+
+	names := []string{}
+	for _, network := range networks {
+		names = append(names, network.Name)
+	}
+	assert.Equal(t, names, []string{"node-1/bridge", "node-2/bridge", "backend", "node-2/private"})
+	assert.Len(t, networks[2].Containers, 2)
+
+	// The networks of the engines are left untouched.
+	assert.Equal(t, testNetworks()[0].Name, "bridge")
+}
+
+func TestEngineNetworks(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /networks":
+			io.WriteString(w, `[{"Name":"bridge","Id":"b1","Scope":"local","Driver":"bridge"}]`)
+		case "POST /networks/create":
+			var request NetworkCreateRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			assert.Equal(t, request.Name, "backend")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"Id":"n1","Warning":""}`)
+		case "POST /networks/b1/connect":
+			var request map[string]string
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			assert.Equal(t, request["Container"], "c1")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "no such network")
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(server.Listener.Addr().String(), 0)
+	engine.Name = "node-1"
+	engine.Version = "1.9.0"
+	client := mockclient.NewMockClient()
+	client.On("InspectContainer", "c1").Return(&dockerclient.ContainerInfo{}, nil)
+	client.On("ListContainers", mock.Anything, mock.Anything, mock.Anything).Return([]dockerclient.Container{}, nil)
+	engine.client = client
+
+	assert.NoError(t, engine.RefreshNetworks())
+	networks := engine.Networks()
+	assert.Len(t, networks, 1)
+	assert.Equal(t, networks[0].ID, "b1")
+	assert.Equal(t, networks[0].Engine, engine)
+
+	response, err := engine.CreateNetwork(&NetworkCreateRequest{Name: "backend", Driver: "bridge"})
+	assert.NoError(t, err)
+	assert.Equal(t, response.ID, "n1")
+
+	assert.NoError(t, engine.ConnectNetwork(&Container{Container: dockerclient.Container{Id: "c1"}}, networks[0]))
+
+	err = engine.RemoveNetwork(&Network{ID: "missing", Name: "missing"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no such network")
+
+	// Engines older than 1.9 have no networks API.
+	requests = nil
+	engine.Version = "1.8.2"
+	assert.NoError(t, engine.RefreshNetworks())
+	assert.Empty(t, requests)
+}
+
+func TestEngineNetworksRefresh(t *testing.T) {
+	var connections, refreshes int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/networks", r.URL.Path)
+		atomic.AddInt32(&refreshes, 1)
+		io.WriteString(w, `[{"Name":"bridge","Id":"b1","Scope":"local","Driver":"bridge"}]`)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	engine := NewEngine(server.Listener.Addr().String(), 0)
+	engine.Version = "1.9.0"
+	engine.tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	client := mockclient.NewMockClient()
+	client.On("InspectContainer", "c1").Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{}}, nil)
+	client.On("ListContainers", mock.Anything, mock.Anything, mock.Anything).Return([]dockerclient.Container{{Id: "c1"}}, nil)
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	engine.client = client
+
+	// The networks are refreshed when containers start and die only...
+	for _, status := range []string{"start", "pause", "unpause", "kill", "die"} {
+		engine.handler(&dockerclient.Event{Id: "c1", Status: status}, nil)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&refreshes))
+
+	// ...And the refreshes reuse the connection to the engine.
+	assert.NoError(t, engine.RefreshNetworks())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&refreshes))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+}
@@ -0,0 +1,116 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/skarademir/naturalsort"
+)
+
+// NodeLabelPrefix namespaces the labels set on a node by the manager, so that
+// they don't collide with the labels of the daemon.
+const NodeLabelPrefix = "com.docker.swarm.label."
+
+var nodeLabelRegexp = regexp.MustCompile(`^(?i)[a-z_][a-z0-9\-_.]*$`)
+
+// ValidateNodeLabel checks that a manager label can be used in constraints.
+func ValidateNodeLabel(key string) error {
+	if !nodeLabelRegexp.MatchString(key) {
+		return fmt.Errorf("invalid node label %q, it should only contain letters, digits, '-', '_' and '.'", key)
+	}
+	return nil
+}
+
+// NodeResources are the resources of a node, overcommit included, how much of
+// them is available to containers once the system reservation is subtracted,
+// how much of them is reserved by containers, the sum of the limits of the
+// containers, which burstable containers can use beyond their reservation,
+// and the data space of the storage driver when it reports it.
+type NodeResources struct {
+	Cpus              float64
+	Memory            int64
+	AllocatableCpus   float64
+	AllocatableMemory int64
+	ReservedCpus      float64
+	ReservedMemory    int64
+	LimitedCpus       float64
+	LimitedMemory     int64
+	Disk              int64
+	UsedDisk          int64
+}
+
+// NodeInfo is the description of a node of the cluster.
+type NodeInfo struct {
+	ID         string
+	Name       string
+	Addr       string
+	IP         string
+	State      string
+	Labels     map[string]string
+	Resources  NodeResources
+	Containers int
+	Images     int
+	Version    string
+	UpdatedAt  time.Time
+}
+
+// NewNodeInfo describes the node of an engine.
+func NewNodeInfo(e *Engine) *NodeInfo {
+	state := "healthy"
+	if !e.IsHealthy() {
+		state = "unhealthy"
+	}
+
+	e.RLock()
+	labels := make(map[string]string, len(e.Labels))
+	for k, v := range e.Labels {
+		labels[k] = v
+	}
+	info := &NodeInfo{
+		ID:         e.ID,
+		Name:       e.Name,
+		Addr:       e.Addr,
+		IP:         e.IP,
+		State:      state,
+		Labels:     labels,
+		Containers: len(e.containers),
+		Images:     len(e.images),
+		Version:    e.Version,
+		UpdatedAt:  e.updatedAt,
+	}
+	e.RUnlock()
+
+	info.Resources = NodeResources{
+		Cpus:              float64(e.TotalMilliCpus()) / 1000,
+		Memory:            e.TotalMemory(),
+		AllocatableCpus:   float64(e.AllocatableMilliCpus()) / 1000,
+		AllocatableMemory: e.AllocatableMemory(),
+		ReservedCpus:      float64(e.UsedMilliCpus()) / 1000,
+		ReservedMemory:    e.UsedMemory(),
+		LimitedCpus:       float64(e.LimitedMilliCpus()) / 1000,
+		LimitedMemory:     e.LimitedMemory(),
+		Disk:              e.DiskTotal,
+		UsedDisk:          e.DiskUsed,
+	}
+	return info
+}
+
+// NodeInfoSorter implements the Sort interface to sort nodes by name, like
+// EngineSorter.
+type NodeInfoSorter []*NodeInfo
+
+// Len returns the number of nodes to be sorted.
+func (s NodeInfoSorter) Len() int {
+	return len(s)
+}
+
+// Swap exchanges the nodes with indices i and j.
+func (s NodeInfoSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less reports whether the node with index i should sort before the node with index j.
+func (s NodeInfoSorter) Less(i, j int) bool {
+	return naturalsort.NaturalSort([]string{s[i].Name, s[j].Name}).Less(0, 1)
+}
@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewNodeInfo(t *testing.T) {
+	engine := NewEngine("127.0.0.1:2375", 0)
+	engine.IP = "127.0.0.1"
+
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(&dockerclient.Info{ID: "id", Name: "name", NCPU: 10, MemTotal: 20, Labels: []string{"foo=bar"}}, nil)
+	client.On("Version").Return(mockVersion, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{{Id: "one"}}, nil)
+	client.On("InspectContainer", "one").Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{Memory: 4, CpuShares: 1024, Labels: map[string]string{SwarmLabelNamespace + ".reserve.memory": "2"}}}, nil)
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{{Id: "image"}}, nil)
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	assert.NoError(t, engine.ConnectWithClient(client))
+
+	info := NewNodeInfo(engine)
+	assert.Equal(t, "id", info.ID)
+	assert.Equal(t, "name", info.Name)
+	assert.Equal(t, "127.0.0.1:2375", info.Addr)
+	assert.Equal(t, "127.0.0.1", info.IP)
+	assert.Equal(t, "healthy", info.State)
+	assert.Equal(t, "bar", info.Labels["foo"])
+	assert.Equal(t, NodeResources{Cpus: 10, Memory: 20, AllocatableCpus: 10, AllocatableMemory: 20, ReservedCpus: 10, ReservedMemory: 2, LimitedCpus: 10, LimitedMemory: 4}, info.Resources)
+	assert.Equal(t, 1, info.Containers)
+	assert.Equal(t, 1, info.Images)
+	assert.Equal(t, "1.6.2", info.Version)
+	assert.False(t, info.UpdatedAt.IsZero())
+
+	// The labels of the description are a copy.
+	info.Labels["foo"] = "baz"
+	assert.Equal(t, "bar", engine.Labels["foo"])
+}
+
+func TestNodeInfoSorter(t *testing.T) {
+	nodes := []*NodeInfo{{Name: "node-10"}, {Name: "node-2"}, {Name: "node-1"}}
+	sort.Sort(NodeInfoSorter(nodes))
+	assert.Equal(t, "node-1", nodes[0].Name)
+	assert.Equal(t, "node-2", nodes[1].Name)
+	assert.Equal(t, "node-10", nodes[2].Name)
+}
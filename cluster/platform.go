@@ -0,0 +1,95 @@
+package cluster
+
+import "strings"
+
+// Platform is the operating system and the architecture of an engine or of
+// an image. Empty fields are unknown.
+type Platform struct {
+	OS           string
+	Architecture string
+}
+
+// String returns the platform as os/architecture.
+func (p Platform) String() string {
+	return p.OS + "/" + p.Architecture
+}
+
+// Compatible returns true if an image of platform p can run on an engine of
+// platform other. Unknown fields are compatible with anything.
+func (p Platform) Compatible(other Platform) bool {
+	if p.OS != "" && other.OS != "" && !strings.EqualFold(p.OS, other.OS) {
+		return false
+	}
+	if p.Architecture != "" && other.Architecture != "" && normalizeArchitecture(p.Architecture) != normalizeArchitecture(other.Architecture) {
+		return false
+	}
+	return true
+}
+
+// normalizeArchitecture maps the names the kernel and older images use for
+// an architecture to the name used by Go and the Docker daemon.
+func normalizeArchitecture(arch string) string {
+	switch arch = strings.ToLower(arch); arch {
+	case "x86_64", "x86-64":
+		return "amd64"
+	case "i386", "i686":
+		return "386"
+	case "aarch64":
+		return "arm64"
+	case "armhf", "armel", "armv6l", "armv7l":
+		return "arm"
+	}
+	return arch
+}
+
+// Platform returns the platform of the engine, from its automatic labels.
+func (e *Engine) Platform() Platform {
+	e.RLock()
+	defer e.RUnlock()
+
+	return Platform{OS: e.Labels["ostype"], Architecture: e.Labels["architecture"]}
+}
+
+// Platform returns the platform of the image. It is inspected on its engine
+// the first time and then cached, images being immutable.
+func (image *Image) Platform() (Platform, error) {
+	e := image.Engine
+	if e == nil {
+		return Platform{}, nil
+	}
+
+	e.RLock()
+	platform, ok := e.imagePlatforms[image.Id]
+	e.RUnlock()
+	if ok {
+		return platform, nil
+	}
+
+	info, err := e.client.InspectImage(image.Id)
+	if err != nil {
+		return Platform{}, err
+	}
+	platform = Platform{OS: info.Os, Architecture: normalizeArchitecture(info.Architecture)}
+
+	e.Lock()
+	if e.imagePlatforms == nil {
+		e.imagePlatforms = make(map[string]Platform)
+	}
+	e.imagePlatforms[image.Id] = platform
+	e.Unlock()
+	return platform, nil
+}
+
+// infoBool converts the boolean fields of the daemon info, reported as 0 or
+// 1 by older daemons.
+func infoBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPlatformCompatible(t *testing.T) {
+	linux := Platform{OS: "linux", Architecture: "amd64"}
+	assert.Equal(t, "linux/amd64", linux.String())
+	assert.True(t, linux.Compatible(Platform{OS: "linux", Architecture: "x86_64"}))
+	assert.True(t, linux.Compatible(Platform{}))
+	assert.True(t, Platform{OS: "linux"}.Compatible(Platform{OS: "linux", Architecture: "arm"}))
+	assert.False(t, linux.Compatible(Platform{OS: "linux", Architecture: "arm"}))
+	assert.False(t, linux.Compatible(Platform{OS: "windows", Architecture: "amd64"}))
+	assert.True(t, Platform{Architecture: "armhf"}.Compatible(Platform{Architecture: "arm"}))
+}
+
+func TestEnginePlatform(t *testing.T) {
+	engine := NewEngine("test", 0)
+
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(&dockerclient.Info{ID: "id", Name: "name", NCPU: 1, MemTotal: 1024, MemoryLimit: true, SwapLimit: float64(0)}, nil)
+	client.On("Version").Return(&dockerclient.Version{Version: "1.9.0", Os: "linux", Arch: "arm"}, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{{Id: "image", RepoTags: []string{"busybox:latest"}}}, nil)
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	assert.NoError(t, engine.ConnectWithClient(client))
+
+	assert.Equal(t, Platform{OS: "linux", Architecture: "arm"}, engine.Platform())
+	assert.Equal(t, "1.9.0", engine.Labels["engineversion"])
+	assert.Equal(t, "true", engine.Labels["memorylimit"])
+	assert.Equal(t, "false", engine.Labels["swaplimit"])
+
+	// The platform of images is inspected once.
+	client.On("InspectImage", "image").Return(&dockerclient.ImageInfo{Id: "image", Os: "linux", Architecture: "armv7l"}, nil).Once()
+	image := engine.Image("busybox")
+	for i := 0; i < 2; i++ {
+		platform, err := image.Platform()
+		assert.NoError(t, err)
+		assert.Equal(t, Platform{OS: "linux", Architecture: "arm"}, platform)
+	}
+	client.Mock.AssertExpectations(t)
+}
@@ -0,0 +1,23 @@
+package cluster
+
+import "encoding/json"
+
+// ProgressMessage is a progress message streamed by a Docker daemon, for
+// instance for each layer of an image being pulled.
+type ProgressMessage struct {
+	ID             string          `json:"id,omitempty"`
+	Status         string          `json:"status,omitempty"`
+	Progress       string          `json:"progress,omitempty"`
+	ProgressDetail json.RawMessage `json:"progressDetail,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// PullOptions restricts the engines an image is pulled on.
+type PullOptions struct {
+	// Constraints the engines must match, with the syntax of the
+	// constraint filter.
+	Constraints []string
+	// Maximum number of engines pulling at the same time, 0 for the
+	// default of the cluster.
+	Concurrency int
+}
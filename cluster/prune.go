@@ -0,0 +1,155 @@
+package cluster
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ImagePrunePolicy selects the images removed from the engines. Images used
+// by a container of the engine, and the layers of the images which are kept,
+// are never removed.
+type ImagePrunePolicy struct {
+	// Images created less than MinAge ago are kept.
+	MinAge time.Duration
+	// Images matching one of these names (ex. redis, redis:3.0) are kept.
+	Keep []string
+	// The KeepTags most recent images of every repository are kept.
+	KeepTags int
+	// Only untagged images are removed.
+	DanglingOnly bool
+	// The images are reported but not removed.
+	DryRun bool
+}
+
+// ImagePruneReport lists the images removed from an engine.
+type ImagePruneReport struct {
+	Node      string
+	Removed   []string
+	Reclaimed int64
+	Errors    []string `json:",omitempty"`
+}
+
+// repository returns the repository of a repo:tag.
+func repository(repoTag string) string {
+	if i := strings.LastIndex(repoTag, ":"); i > strings.LastIndex(repoTag, "/") {
+		return repoTag[:i]
+	}
+	return repoTag
+}
+
+// tags returns the tags of the image, without the <none>:<none> placeholder.
+func (image *Image) tags() []string {
+	tags := []string{}
+	for _, tag := range image.RepoTags {
+		if tag != "<none>:<none>" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// keep returns true if the policy keeps the image.
+func (p *ImagePrunePolicy) keep(image *Image, now time.Time) bool {
+	if p.MinAge > 0 && now.Sub(time.Unix(image.Created, 0)) < p.MinAge {
+		return true
+	}
+	if p.DanglingOnly && len(image.tags()) > 0 {
+		return true
+	}
+	for _, name := range p.Keep {
+		if image.Match(name, strings.Contains(name[strings.LastIndex(name, "/")+1:], ":")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the images of the engine the policy removes.
+func (p *ImagePrunePolicy) Select(e *Engine) []*Image {
+	images := e.Images(true)
+
+	used := make(map[string]bool)
+	for _, container := range e.Containers() {
+		used[container.Info.Image] = true
+		for _, image := range images {
+			if image.Match(container.Image, true) {
+				used[image.Id] = true
+			}
+		}
+	}
+
+	// Only the images which aren't the parent of another one can be
+	// removed: the others are layers.
+	parents := make(map[string]bool)
+	for _, image := range images {
+		parents[image.ParentId] = true
+	}
+
+	// The most recent images of each repository are kept.
+	kept := make(map[string]bool)
+	if p.KeepTags > 0 {
+		sorted := append([]*Image{}, images...)
+		sort.Sort(sort.Reverse(imagesByCreation(sorted)))
+		count := make(map[string]int)
+		for _, image := range sorted {
+			for _, tag := range image.tags() {
+				repo := repository(tag)
+				if count[repo] < p.KeepTags {
+					count[repo]++
+					kept[image.Id] = true
+				}
+			}
+		}
+	}
+
+	now := time.Now()
+	selected := []*Image{}
+	for _, image := range images {
+		if used[image.Id] || parents[image.Id] || kept[image.Id] || p.keep(image, now) {
+			continue
+		}
+		selected = append(selected, image)
+	}
+	return selected
+}
+
+// PruneImages removes the images of the engine selected by the policy.
+func (e *Engine) PruneImages(policy *ImagePrunePolicy) *ImagePruneReport {
+	report := &ImagePruneReport{Node: e.Name, Removed: []string{}}
+
+	for _, image := range policy.Select(e) {
+		// Tagged images are removed one tag at a time, so that images
+		// with several tags are removed without forcing it.
+		names := image.tags()
+		if len(names) == 0 {
+			names = []string{image.Id}
+		}
+
+		removed := true
+		if !policy.DryRun {
+			for _, name := range names {
+				if _, err := e.RemoveImage(image, name, false); err != nil {
+					report.Errors = append(report.Errors, name+": "+err.Error())
+					removed = false
+					break
+				}
+			}
+		}
+		if removed {
+			report.Removed = append(report.Removed, names...)
+			report.Reclaimed += image.Size
+		}
+	}
+
+	if !policy.DryRun {
+		e.RefreshImages()
+	}
+	return report
+}
+
+type imagesByCreation []*Image
+
+func (images imagesByCreation) Len() int           { return len(images) }
+func (images imagesByCreation) Swap(i, j int)      { images[i], images[j] = images[j], images[i] }
+func (images imagesByCreation) Less(i, j int) bool { return images[i].Created < images[j].Created }
@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func pruneEngine() *Engine {
+	old := time.Now().Add(-48 * time.Hour).Unix()
+	engine := NewEngine("test", 0)
+	engine.Name = "node-1"
+	for _, image := range []dockerclient.Image{
+		{Id: "base", RepoTags: []string{"<none>:<none>"}, Created: old - 100},
+		{Id: "redis1", ParentId: "base", RepoTags: []string{"redis:2.8"}, Created: old - 20, Size: 10},
+		{Id: "redis2", ParentId: "base", RepoTags: []string{"redis:3.0", "redis:latest"}, Created: old - 10, Size: 20},
+		{Id: "app1", ParentId: "base", RepoTags: []string{"registry:5000/app:1"}, Created: old - 20, Size: 30},
+		{Id: "app2", ParentId: "base", RepoTags: []string{"registry:5000/app:2"}, Created: old - 10, Size: 40},
+		{Id: "dangling", ParentId: "base", RepoTags: []string{"<none>:<none>"}, Created: old, Size: 50},
+		{Id: "recent", ParentId: "base", RepoTags: []string{"recent:latest"}, Created: time.Now().Unix(), Size: 60},
+	} {
+		engine.images = append(engine.images, &Image{Image: image, Engine: engine})
+	}
+	engine.containers = map[string]*Container{
+		"c1": {Container: dockerclient.Container{Id: "c1", Image: "registry:5000/app:1"}, Engine: engine},
+	}
+	return engine
+}
+
+func selectedIds(images []*Image) []string {
+	ids := []string{}
+	for _, image := range images {
+		ids = append(ids, image.Id)
+	}
+	return ids
+}
+
+func TestRepository(t *testing.T) {
+	assert.Equal(t, repository("redis:latest"), "redis")
+	assert.Equal(t, repository("redis"), "redis")
+	assert.Equal(t, repository("registry:5000/app:1"), "registry:5000/app")
+	assert.Equal(t, repository("registry:5000/app"), "registry:5000/app")
+}
+
+func TestPrunePolicySelect(t *testing.T) {
+	engine := pruneEngine()
+
+	// Layers and images used by a container are never selected.
+	policy := &ImagePrunePolicy{}
+	assert.Equal(t, selectedIds(policy.Select(engine)), []string{"redis1", "redis2", "app2", "dangling", "recent"})
+
+	policy = &ImagePrunePolicy{MinAge: time.Hour}
+	assert.Equal(t, selectedIds(policy.Select(engine)), []string{"redis1", "redis2", "app2", "dangling"})
+
+	policy = &ImagePrunePolicy{MinAge: time.Hour, Keep: []string{"redis:2.8", "registry:5000/app"}}
+	assert.Equal(t, selectedIds(policy.Select(engine)), []string{"redis2", "dangling"})
+
+	policy = &ImagePrunePolicy{MinAge: time.Hour, KeepTags: 1}
+	assert.Equal(t, selectedIds(policy.Select(engine)), []string{"redis1", "dangling"})
+
+	policy = &ImagePrunePolicy{DanglingOnly: true}
+	assert.Equal(t, selectedIds(policy.Select(engine)), []string{"dangling"})
+}
+
+func TestPruneImages(t *testing.T) {
+	engine := pruneEngine()
+	client := mockclient.NewMockClient()
+	engine.client = client
+
+	// Nothing is removed in dry-run mode.
+	report := engine.PruneImages(&ImagePrunePolicy{MinAge: time.Hour, KeepTags: 1, DryRun: true})
+	assert.Equal(t, report.Node, "node-1")
+	assert.Equal(t, report.Removed, []string{"redis:2.8", "dangling"})
+	assert.Equal(t, report.Reclaimed, int64(60))
+	client.Mock.AssertExpectations(t)
+
+	// Every tag of an image is removed, and failures are reported.
+	client.On("RemoveImage", "redis:2.8", false).Return([]*dockerclient.ImageDelete{}, nil).Once()
+	client.On("RemoveImage", "redis:3.0", false).Return([]*dockerclient.ImageDelete{}, nil).Once()
+	client.On("RemoveImage", "redis:latest", false).Return([]*dockerclient.ImageDelete{}, nil).Once()
+	client.On("RemoveImage", "registry:5000/app:2", false).Return([]*dockerclient.ImageDelete{}, errors.New("conflict")).Once()
+	client.On("RemoveImage", "dangling", false).Return([]*dockerclient.ImageDelete{}, nil).Once()
+	client.On("ListImages", true).Return([]*dockerclient.Image{}, nil).Once()
+
+	report = engine.PruneImages(&ImagePrunePolicy{MinAge: time.Hour})
+	assert.Equal(t, report.Removed, []string{"redis:2.8", "redis:3.0", "redis:latest", "dangling"})
+	assert.Equal(t, report.Reclaimed, int64(80))
+	assert.Equal(t, report.Errors, []string{"registry:5000/app:2: conflict"})
+	assert.Empty(t, engine.Images(true))
+	client.Mock.AssertExpectations(t)
+}
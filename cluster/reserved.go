@@ -0,0 +1,102 @@
+package cluster
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/units"
+)
+
+const (
+	// Daemon labels overriding the resources reserved for the system (OS,
+	// daemon, agents) on an engine.
+	reservedMemoryLabel = "com.docker.swarm.reserved.memory"
+	reservedCpusLabel   = "com.docker.swarm.reserved.cpus"
+
+	// Manager labels overriding the resources reserved for the system. They
+	// take precedence over the daemon labels.
+	reservedMemoryNodeLabel = "reserved.memory"
+	reservedCpusNodeLabel   = "reserved.cpus"
+)
+
+// ParseSystemReserved reads the default resources reserved for the system on
+// every engine from the cluster options swarm.reserved.memory and
+// swarm.reserved.cpus. CPUs are in thousandths of a CPU.
+func ParseSystemReserved(options DriverOpts) (memory, milliCpus int64, err error) {
+	if value, ok := options.String("swarm.reserved.memory", ""); ok {
+		if memory, err = units.RAMInBytes(value); err != nil || memory < 0 {
+			return 0, 0, fmt.Errorf("invalid value %q for swarm.reserved.memory", value)
+		}
+	}
+	if value, ok := options.String("swarm.reserved.cpus", ""); ok {
+		if milliCpus, err = ParseCpus(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid value %q for swarm.reserved.cpus", value)
+		}
+	}
+	return memory, milliCpus, nil
+}
+
+// SetSystemReserved sets the resources reserved for the system on the engine
+// unless a label overrides them. CPUs are in thousandths of a CPU.
+func (e *Engine) SetSystemReserved(memory, milliCpus int64) {
+	e.Lock()
+	e.reservedMemory = memory
+	e.reservedMilliCpus = milliCpus
+	e.Unlock()
+}
+
+// SystemReservedMemory returns the memory reserved for the system.
+func (e *Engine) SystemReservedMemory() int64 {
+	e.RLock()
+	defer e.RUnlock()
+
+	return e.reservedResource(reservedMemoryNodeLabel, reservedMemoryLabel, e.reservedMemory, units.RAMInBytes)
+}
+
+// SystemReservedMilliCpus returns the CPUs reserved for the system, in
+// thousandths of a CPU.
+func (e *Engine) SystemReservedMilliCpus() int64 {
+	e.RLock()
+	defer e.RUnlock()
+
+	return e.reservedResource(reservedCpusNodeLabel, reservedCpusLabel, e.reservedMilliCpus, ParseCpus)
+}
+
+// reservedResource returns the value of the manager label or, if it's not set
+// or invalid, of the daemon label or, failing that, the default value.
+func (e *Engine) reservedResource(nodeLabel, label string, value int64, parse func(string) (int64, error)) int64 {
+	candidates := []string{}
+	if v, ok := e.nodeLabels[nodeLabel]; ok {
+		candidates = append(candidates, v)
+	}
+	if v, ok := e.Labels[label]; ok {
+		candidates = append(candidates, v)
+	}
+
+	for _, candidate := range candidates {
+		if v, err := parse(candidate); err == nil && v >= 0 {
+			return v
+		}
+		log.WithFields(log.Fields{"name": e.Name, "id": e.ID}).Debugf("Ignoring invalid system reservation %q", candidate)
+	}
+	return value
+}
+
+// AllocatableMemory returns the memory available to containers: the total
+// memory, overcommit included, minus the memory reserved for the system.
+func (e *Engine) AllocatableMemory() int64 {
+	if memory := e.TotalMemory() - e.SystemReservedMemory(); memory > 0 {
+		return memory
+	}
+	return 0
+}
+
+// AllocatableMilliCpus returns the CPUs available to containers, in
+// thousandths of a CPU: the total CPUs, overcommit included, minus the CPUs
+// reserved for the system.
+func (e *Engine) AllocatableMilliCpus() int64 {
+	if milliCpus := e.TotalMilliCpus() - e.SystemReservedMilliCpus(); milliCpus > 0 {
+		return milliCpus
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RestartPolicy is the cluster-level restart policy of a container, set with
+// the com.docker.swarm.restart label (ex. on-failure:5,relocate-after=3).
+// Unlike the engine restart policy, the container may be relocated to a
+// different node when it keeps failing.
+type RestartPolicy struct {
+	// Name is either "always" or "on-failure".
+	Name string
+	// MaximumRetryCount is the maximum number of restarts, 0 means no limit.
+	MaximumRetryCount int
+	// RelocateAfter is the number of failures on the same node after which
+	// the container is recreated on another node, 0 means never. OOM kills
+	// always trigger a relocation.
+	RelocateAfter int
+}
+
+// ParseRestartPolicy parses a restart policy of the form
+// always|on-failure[:max][,relocate-after=n].
+func ParseRestartPolicy(value string) (*RestartPolicy, error) {
+	parts := strings.Split(value, ",")
+	policy := &RestartPolicy{}
+
+	name := strings.SplitN(parts[0], ":", 2)
+	policy.Name = name[0]
+	switch policy.Name {
+	case "always":
+		if len(name) == 2 {
+			return nil, fmt.Errorf("maximum restart count not valid with restart policy %q", policy.Name)
+		}
+	case "on-failure":
+		if len(name) == 2 {
+			count, err := strconv.Atoi(name[1])
+			if err != nil || count < 0 {
+				return nil, fmt.Errorf("invalid maximum restart count %q", name[1])
+			}
+			policy.MaximumRetryCount = count
+		}
+	default:
+		return nil, fmt.Errorf("invalid restart policy %q", policy.Name)
+	}
+
+	for _, option := range parts[1:] {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 || kv[0] != "relocate-after" {
+			return nil, fmt.Errorf("invalid restart policy option %q", option)
+		}
+		count, err := strconv.Atoi(kv[1])
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("invalid relocate-after count %q", kv[1])
+		}
+		policy.RelocateAfter = count
+	}
+
+	return policy, nil
+}
+
+// ShouldRestart returns whether a container that exited with exitCode after
+// having been restarted restarts times should be restarted again.
+func (p *RestartPolicy) ShouldRestart(exitCode, restarts int) bool {
+	if p.Name == "on-failure" && exitCode == 0 {
+		return false
+	}
+	return p.MaximumRetryCount == 0 || restarts < p.MaximumRetryCount
+}
+
+// RestartStatus reports what the cluster-level restart policy did to a
+// container. It is exposed in the inspect output.
+type RestartStatus struct {
+	Count         int
+	Relocations   int
+	LastFailure   string
+	LastFailureAt time.Time
+}
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRestartPolicy(t *testing.T) {
+	policy, err := ParseRestartPolicy("always")
+	assert.NoError(t, err)
+	assert.Equal(t, policy, &RestartPolicy{Name: "always"})
+
+	policy, err = ParseRestartPolicy("on-failure")
+	assert.NoError(t, err)
+	assert.Equal(t, policy, &RestartPolicy{Name: "on-failure"})
+
+	policy, err = ParseRestartPolicy("on-failure:5,relocate-after=3")
+	assert.NoError(t, err)
+	assert.Equal(t, policy, &RestartPolicy{Name: "on-failure", MaximumRetryCount: 5, RelocateAfter: 3})
+
+	policy, err = ParseRestartPolicy("always,relocate-after=1")
+	assert.NoError(t, err)
+	assert.Equal(t, policy, &RestartPolicy{Name: "always", RelocateAfter: 1})
+
+	for _, value := range []string{"", "no", "always:3", "on-failure:", "on-failure:-1", "on-failure:x", "on-failure,relocate", "on-failure,relocate-after=x", "on-failure,foo=1"} {
+		_, err := ParseRestartPolicy(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestShouldRestart(t *testing.T) {
+	always := &RestartPolicy{Name: "always"}
+	assert.True(t, always.ShouldRestart(0, 0))
+	assert.True(t, always.ShouldRestart(1, 100))
+
+	onFailure := &RestartPolicy{Name: "on-failure", MaximumRetryCount: 2}
+	assert.False(t, onFailure.ShouldRestart(0, 0))
+	assert.True(t, onFailure.ShouldRestart(1, 0))
+	assert.True(t, onFailure.ShouldRestart(137, 1))
+	assert.False(t, onFailure.ShouldRestart(1, 2))
+}
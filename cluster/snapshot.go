@@ -0,0 +1,135 @@
+package cluster
+
+import (
+	"strings"
+
+	"github.com/samalba/dockerclient"
+)
+
+// EngineSnapshot is a compact, serializable copy of the state of an engine.
+// It is used to restore the cluster state before the engine is reachable.
+type EngineSnapshot struct {
+	ID         string
+	IP         string
+	Addr       string
+	Name       string
+	Cpus       int64
+	Memory     int64
+	DiskUsed   int64
+	DiskTotal  int64
+	Labels     map[string]string
+	Containers []*ContainerSnapshot
+	Images     []dockerclient.Image
+}
+
+// ContainerSnapshot is the compact state of a container kept in an
+// EngineSnapshot: identifiers, names, config labels (which hold the Swarm ID,
+// affinities and constraints) and reserved resources.
+type ContainerSnapshot struct {
+	dockerclient.Container
+
+	State     dockerclient.State
+	Memory    int64
+	CpuShares int64
+}
+
+// Snapshot returns a compact copy of the current state of the engine.
+func (e *Engine) Snapshot() *EngineSnapshot {
+	e.RLock()
+	defer e.RUnlock()
+
+	s := &EngineSnapshot{
+		ID:         e.ID,
+		IP:         e.IP,
+		Addr:       e.Addr,
+		Name:       e.Name,
+		Cpus:       e.Cpus,
+		Memory:     e.Memory,
+		DiskUsed:   e.DiskUsed,
+		DiskTotal:  e.DiskTotal,
+		Labels:     e.Labels,
+		Containers: make([]*ContainerSnapshot, 0, len(e.containers)),
+		Images:     make([]dockerclient.Image, 0, len(e.images)),
+	}
+
+	for _, container := range e.containers {
+		cs := &ContainerSnapshot{Container: container.Container}
+		// Labels from the config are the authoritative ones (Swarm ID, ...).
+		if container.Config != nil {
+			cs.Labels = container.Config.Labels
+			cs.Memory = container.Config.Memory
+			cs.CpuShares = container.Config.CpuShares
+		}
+		// Ports and sizes are refreshed as soon as the engine is reachable.
+		cs.Ports = nil
+		if container.Info.State != nil {
+			cs.State = *container.Info.State
+		}
+		s.Containers = append(s.Containers, cs)
+	}
+
+	for _, image := range e.images {
+		s.Images = append(s.Images, image.Image)
+	}
+
+	return s
+}
+
+// NewEngineFromSnapshot creates a disconnected engine pre-populated with the
+// state of a snapshot. The engine counts as healthy, so that containers are
+// scheduled from the snapshot, until connecting to it fails. Once Connect
+// succeeds, the snapshot state is replaced by the real one.
+func NewEngineFromSnapshot(s *EngineSnapshot, overcommitRatio float64) *Engine {
+	e := NewEngine(s.Addr, overcommitRatio)
+	e.ID = s.ID
+	e.IP = s.IP
+	e.Name = s.Name
+	e.Cpus = s.Cpus
+	e.Memory = s.Memory
+	e.DiskUsed = s.DiskUsed
+	e.DiskTotal = s.DiskTotal
+	if s.Labels != nil {
+		e.Labels = s.Labels
+		// Keep the manager labels across the first specs update.
+		e.nodeLabels = make(map[string]string)
+		for k, v := range s.Labels {
+			if strings.HasPrefix(k, NodeLabelPrefix) {
+				e.nodeLabels[strings.TrimPrefix(k, NodeLabelPrefix)] = v
+			}
+		}
+	}
+
+	for _, cs := range s.Containers {
+		state := cs.State
+		config := BuildContainerConfig(dockerclient.ContainerConfig{
+			Image:     cs.Image,
+			Labels:    cs.Labels,
+			Memory:    cs.Memory,
+			CpuShares: cs.CpuShares,
+		})
+		e.containers[cs.Id] = &Container{
+			Container: cs.Container,
+			Config:    config,
+			Info: dockerclient.ContainerInfo{
+				Id:    cs.Id,
+				Image: cs.Image,
+				State: &state,
+			},
+			Engine: e,
+		}
+	}
+
+	for _, image := range s.Images {
+		e.images = append(e.images, &Image{Image: image, Engine: e})
+	}
+
+	return e
+}
+
+// SetUnreachable flags an engine restored from a snapshot as unhealthy, once
+// connecting to it failed.
+func (e *Engine) SetUnreachable() {
+	if e.setHealthy(false) {
+		e.emitEvent("engine_disconnect")
+	}
+}
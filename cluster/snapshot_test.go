@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineSnapshot(t *testing.T) {
+	engine := NewEngine("127.0.0.1:2375", 0)
+	engine.ID = "id"
+	engine.IP = "127.0.0.1"
+	engine.Name = "name"
+	engine.Cpus = 4
+	engine.Memory = 1024
+	engine.Labels = map[string]string{"foo": "bar"}
+
+	config := BuildContainerConfig(dockerclient.ContainerConfig{Memory: 512, CpuShares: 1})
+	config.SetSwarmID("swarm-id")
+	engine.AddContainer(&Container{
+		Container: dockerclient.Container{Id: "container-id", Names: []string{"/container-name"}, Image: "busybox"},
+		Config:    config,
+		Info:      dockerclient.ContainerInfo{State: &dockerclient.State{Running: true}},
+		Engine:    engine,
+	})
+	engine.addImage(&Image{Image: dockerclient.Image{Id: "image-id", RepoTags: []string{"busybox:latest"}}, Engine: engine})
+
+	// Go through JSON as the snapshot is stored serialized.
+	data, err := json.Marshal(engine.Snapshot())
+	assert.NoError(t, err)
+	s := &EngineSnapshot{}
+	assert.NoError(t, json.Unmarshal(data, s))
+
+	restored := NewEngineFromSnapshot(s, 0)
+	assert.Equal(t, restored.ID, "id")
+	assert.Equal(t, restored.IP, "127.0.0.1")
+	assert.Equal(t, restored.Addr, "127.0.0.1:2375")
+	assert.Equal(t, restored.Name, "name")
+	assert.Equal(t, restored.Cpus, int64(4))
+	assert.Equal(t, restored.Memory, int64(1024))
+	assert.Equal(t, restored.Labels["foo"], "bar")
+
+	// Not connected yet, but available for scheduling.
+	assert.True(t, restored.IsHealthy())
+	assert.False(t, restored.isConnected())
+
+	containers := restored.Containers()
+	if assert.Len(t, containers, 1) {
+		container := containers[0]
+		assert.Equal(t, container.Id, "container-id")
+		assert.Equal(t, container.Names, []string{"/container-name"})
+		assert.Equal(t, container.Config.SwarmID(), "swarm-id")
+		assert.True(t, container.Info.State.Running)
+		assert.Equal(t, container.Engine, restored)
+		assert.Equal(t, containers.Get("swarm-id"), container)
+	}
+	assert.Equal(t, restored.UsedMemory(), int64(512))
+	assert.Equal(t, restored.UsedMilliCpus(), int64(1000))
+
+	assert.NotNil(t, restored.Image("busybox"))
+
+	restored.SetUnreachable()
+	assert.False(t, restored.IsHealthy())
+}
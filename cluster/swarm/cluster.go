@@ -2,18 +2,24 @@ package swarm
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/pkg/units"
+	"github.com/docker/libkv/store"
 	"github.com/docker/swarm/cluster"
 	"github.com/docker/swarm/discovery"
+	kvdiscovery "github.com/docker/swarm/discovery/kv"
 	"github.com/docker/swarm/scheduler"
 	"github.com/docker/swarm/scheduler/node"
 	"github.com/samalba/dockerclient"
@@ -23,54 +29,153 @@ import (
 type Cluster struct {
 	sync.RWMutex
 
-	eventHandler cluster.EventHandler
-	engines      map[string]*cluster.Engine
-	scheduler    *scheduler.Scheduler
-	discovery    discovery.Discovery
-
-	overcommitRatio float64
-	TLSConfig       *tls.Config
+	eventHandlers     []cluster.EventHandler
+	eventHandlersLock sync.RWMutex
+	engines           map[string]*cluster.Engine
+	scheduler         *scheduler.Scheduler
+	discovery         discovery.Discovery
+
+	overcommitRatio   float64
+	reservedMemory    int64
+	reservedMilliCpus int64
+	resourcePolicy    *cluster.ResourcePolicy
+	pinImages         bool
+	pinnedImages      map[string]string
+	pinnedImagesLock  sync.Mutex
+	pullConcurrency   int
+	pruneInterval     time.Duration
+	prunePolicy       *cluster.ImagePrunePolicy
+	TLSConfig         *tls.Config
+
+	primary          bool
+	snapshotStore    store.Store
+	snapshotKey      string
+	snapshotInterval time.Duration
+
+	restarts     map[string]*restartTracker
+	restartsLock sync.Mutex
+
+	nodeLabelsStore store.Store
+	nodeLabelsPath  string
+	nodeLabels      map[string]map[string]string
 }
 
 // NewCluster is exported
 func NewCluster(scheduler *scheduler.Scheduler, TLSConfig *tls.Config, discovery discovery.Discovery, options cluster.DriverOpts) (cluster.Cluster, error) {
 	log.WithFields(log.Fields{"name": "swarm"}).Debug("Initializing cluster")
 
+	reservedMemory, reservedMilliCpus, err := cluster.ParseSystemReserved(options)
+	if err != nil {
+		return nil, err
+	}
+	resourcePolicy, err := cluster.ParseResourcePolicy(options)
+	if err != nil {
+		return nil, err
+	}
+	pruneInterval, prunePolicy, err := parsePruneOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
 	cluster := &Cluster{
-		engines:         make(map[string]*cluster.Engine),
-		scheduler:       scheduler,
-		TLSConfig:       TLSConfig,
-		discovery:       discovery,
-		overcommitRatio: 0.05,
+		engines:           make(map[string]*cluster.Engine),
+		scheduler:         scheduler,
+		TLSConfig:         TLSConfig,
+		discovery:         discovery,
+		overcommitRatio:   0.05,
+		reservedMemory:    reservedMemory,
+		reservedMilliCpus: reservedMilliCpus,
+		restarts:          make(map[string]*restartTracker),
+		nodeLabels:        make(map[string]map[string]string),
+		pinnedImages:      make(map[string]string),
+		resourcePolicy:    resourcePolicy,
+		pruneInterval:     pruneInterval,
+		prunePolicy:       prunePolicy,
 	}
 
 	if val, ok := options.Float("swarm.overcommit", ""); ok {
 		cluster.overcommitRatio = val
 	}
 
+	if val, ok := options.String("swarm.image.pin", ""); ok {
+		pin, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for swarm.image.pin", val)
+		}
+		cluster.pinImages = pin
+	}
+
+	if val, ok := options.Int("swarm.pull.concurrency", ""); ok {
+		if val < 0 {
+			return nil, fmt.Errorf("invalid value %d for swarm.pull.concurrency", val)
+		}
+		cluster.pullConcurrency = int(val)
+	}
+
+	if val, ok := options.String("swarm.snapshotinterval", ""); ok {
+		interval, err := time.ParseDuration(val)
+		if err != nil {
+			return nil, err
+		}
+		cluster.snapshotInterval = interval
+	}
+
+	if cluster.pruneInterval > 0 {
+		go cluster.pruneLoop()
+	}
+
+	kvDiscovery, isKV := discovery.(*kvdiscovery.Discovery)
+	if cluster.snapshotInterval > 0 {
+		if !isKV {
+			return nil, errors.New("swarm.snapshotinterval is only supported with consul, etcd and zookeeper discovery")
+		}
+		cluster.snapshotStore = kvDiscovery.Store()
+		cluster.snapshotKey = path.Join(kvDiscovery.Prefix(), snapshotPath)
+		go cluster.snapshotLoop()
+	}
+
+	if isKV {
+		cluster.nodeLabelsStore = kvDiscovery.Store()
+		cluster.nodeLabelsPath = path.Join(kvDiscovery.Prefix(), nodeLabelsPath)
+		go cluster.watchNodeLabels()
+	}
+
+	// Restore the last known state of the cluster, if any, before watching
+	// the discovery so that the restored engines are not added twice.
+	restored := cluster.restoreSnapshot()
+
 	discoveryCh, errCh := cluster.discovery.Watch(nil)
-	go cluster.monitorDiscovery(discoveryCh, errCh)
+	go cluster.monitorDiscovery(restored, discoveryCh, errCh)
 
 	return cluster, nil
 }
 
 // Handle callbacks for the events
 func (c *Cluster) Handle(e *cluster.Event) error {
-	if c.eventHandler == nil {
-		return nil
+	if e.Status == "engine_update" {
+		c.updateEngineID(e.Engine)
 	}
-	if err := c.eventHandler.Handle(e); err != nil {
-		log.Error(err)
+	c.handleRestartPolicy(e)
+
+	// Fan out the event to every handler.
+	c.eventHandlersLock.RLock()
+	handlers := c.eventHandlers
+	c.eventHandlersLock.RUnlock()
+
+	for _, h := range handlers {
+		if err := h.Handle(e); err != nil {
+			log.Error(err)
+		}
 	}
 	return nil
 }
 
-// RegisterEventHandler registers an event handler.
+// RegisterEventHandler registers an event handler. Every event is sent to
+// all the registered handlers.
 func (c *Cluster) RegisterEventHandler(h cluster.EventHandler) error {
-	if c.eventHandler != nil {
-		return errors.New("event handler already set")
-	}
-	c.eventHandler = h
+	c.eventHandlersLock.Lock()
+	c.eventHandlers = append(c.eventHandlers, h)
+	c.eventHandlersLock.Unlock()
 	return nil
 }
 
@@ -86,37 +191,106 @@ func (c *Cluster) generateUniqueID() string {
 
 // CreateContainer aka schedule a brand new container into the cluster.
 func (c *Cluster) CreateContainer(config *cluster.ContainerConfig, name string) (*cluster.Container, error) {
-	container, err := c.createContainer(config, name, false)
+	return c.createContainerWith(config, name, &placement{})
+}
+
+// placement restricts the engines a container is scheduled on.
+type placement struct {
+	// Engine the container can't be created on.
+	excludedID string
+	// Engine the container must be created on, if any.
+	nodeID string
+	// Container being replaced: its name and its Swarm ID can be reused.
+	replaced *cluster.Container
+}
+
+func (p *placement) replaces(id string) bool {
+	return p.replaced != nil && p.replaced.Id == id
+}
+
+func (p *placement) accepts(n *node.Node) bool {
+	return n.ID != p.excludedID && (p.nodeID == "" || n.ID == p.nodeID)
+}
+
+// createContainerWith schedules a container on the engines the placement
+// accepts.
+func (c *Cluster) createContainerWith(config *cluster.ContainerConfig, name string, p *placement) (*cluster.Container, error) {
+	container, err := c.createContainer(config, name, false, p)
 
 	//  fails with image not found, then try to reschedule with soft-image-affinity
 	if err != nil && strings.HasSuffix(err.Error(), "not found") {
 		// Check if the image exists in the cluster
 		// If exists, retry with a soft-image-affinity
 		if image := c.Image(config.Image); image != nil {
-			container, err = c.createContainer(config, name, true)
+			container, err = c.createContainer(config, name, true, p)
 		}
 	}
 	return container, err
 }
 
-func (c *Cluster) createContainer(config *cluster.ContainerConfig, name string, withSoftImageAffinity bool) (*cluster.Container, error) {
+func (c *Cluster) createContainer(config *cluster.ContainerConfig, name string, withSoftImageAffinity bool, p *placement) (*cluster.Container, error) {
 	c.scheduler.Lock()
 	defer c.scheduler.Unlock()
 
 	// Ensure the name is available
-	if cID := c.getIDFromName(name); cID != "" {
+	if cID := c.getIDFromName(name); cID != "" && !p.replaces(cID) {
 		return nil, fmt.Errorf("Conflict, The name %s is already assigned to %s. You have to delete (or rename) that container to be able to assign %s to a container again.", name, cID, name)
 	}
 
-	// Associate a Swarm ID to the container we are creating.
-	config.SetSwarmID(c.generateUniqueID())
+	// Associate a Swarm ID to the container we are creating. A container
+	// recreated from the config of a previous one (rescheduled, moved to
+	// another engine, ...) keeps its Swarm ID so clients never see it change.
+	if swarmID := config.SwarmID(); swarmID == "" {
+		config.SetSwarmID(c.generateUniqueID())
+	} else if cID := c.getIDFromSwarmID(swarmID); cID != "" && !p.replaces(cID) {
+		return nil, fmt.Errorf("Conflict, The Swarm ID %s is already assigned to %s.", swarmID, cID)
+	}
+
+	nodes := c.listNodes()
+	if p.excludedID != "" || p.nodeID != "" {
+		candidates := make([]*node.Node, 0, len(nodes))
+		for _, n := range nodes {
+			if p.accepts(n) {
+				candidates = append(candidates, n)
+			}
+		}
+		nodes = candidates
+	}
+
+	// Run the same image on every engine: engines with another image under
+	// the same name don't have the image as far as scheduling is concerned.
+	// An image no engine has is pulled where the container would go.
+	if c.pinImages {
+		if err := c.pinImage(config, func() (*cluster.Engine, error) {
+			n, err := c.scheduler.SelectNodeForContainer(nodes, c.resourcePolicy.WithRequests(config))
+			if err != nil {
+				return nil, err
+			}
+			if engine := c.getEngine(n.ID); engine != nil {
+				return engine, nil
+			}
+			return nil, fmt.Errorf("No such node: %s", n.ID)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Enforce the resource bounds, and schedule the containers which don't
+	// request resources with the default requests.
+	if err := c.resourcePolicy.Check(config); err != nil {
+		return nil, err
+	}
+	c.resourcePolicy.Apply(config)
+	if err := config.ValidateReservations(); err != nil {
+		return nil, err
+	}
 
 	configTemp := config
 	if withSoftImageAffinity {
 		configTemp.AddAffinity("image==~" + config.Image)
 	}
 
-	n, err := c.scheduler.SelectNodeForContainer(c.listNodes(), configTemp)
+	n, err := c.scheduler.SelectNodeForContainer(nodes, c.resourcePolicy.WithRequests(configTemp))
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +336,8 @@ func (c *Cluster) addEngine(addr string) bool {
 	}
 
 	engine := cluster.NewEngine(addr, c.overcommitRatio)
+	engine.SetSystemReserved(c.reservedMemory, c.reservedMilliCpus)
+	engine.SetResourcePolicy(c.resourcePolicy)
 	if err := engine.RegisterEventHandler(c); err != nil {
 		log.Error(err)
 	}
@@ -190,6 +366,9 @@ func (c *Cluster) addEngine(addr string) bool {
 
 	// Finally register the engine.
 	c.engines[engine.ID] = engine
+	if labels, ok := c.nodeLabels[engine.ID]; ok {
+		engine.SetNodeLabels(labels)
+	}
 	log.Infof("Registered Engine %s at %s", engine.Name, addr)
 	return true
 }
@@ -208,10 +387,32 @@ func (c *Cluster) removeEngine(addr string) bool {
 	return true
 }
 
+// updateEngineID re-registers an engine under its new ID if it changed, for
+// instance because Docker was reinstalled at the same address.
+func (c *Cluster) updateEngineID(engine *cluster.Engine) {
+	c.Lock()
+	for id, e := range c.engines {
+		if e != engine || id == engine.ID {
+			continue
+		}
+
+		delete(c.engines, id)
+		if old, exists := c.engines[engine.ID]; exists {
+			c.Unlock()
+			log.Errorf("ID duplicated. %s shared by %s and %s", engine.ID, old.Addr, engine.Addr)
+			engine.Disconnect()
+			return
+		}
+		c.engines[engine.ID] = engine
+		log.Infof("Engine %s at %s changed ID from %s to %s", engine.Name, engine.Addr, id, engine.ID)
+		break
+	}
+	c.Unlock()
+}
+
 // Entries are Docker Engines
-func (c *Cluster) monitorDiscovery(ch <-chan discovery.Entries, errCh <-chan error) {
+func (c *Cluster) monitorDiscovery(currentEntries discovery.Entries, ch <-chan discovery.Entries, errCh <-chan error) {
 	// Watch changes on the discovery channel.
-	currentEntries := discovery.Entries{}
 	for {
 		select {
 		case entries := <-ch:
@@ -267,6 +468,87 @@ func (c *Cluster) Image(IDOrName string) *cluster.Image {
 	return nil
 }
 
+// pinImage resolves the image of the container to an image ID, once per image
+// name: every container created with the name afterwards is pinned to the same
+// ID, as long as an engine has it. The name is resolved to the most recent
+// image with the name in the cluster. When no engine has it, the image is
+// first pulled on the engine returned by pullOn.
+func (c *Cluster) pinImage(config *cluster.ContainerConfig, pullOn func() (*cluster.Engine, error)) error {
+	if config.ImageName() != config.Image || strings.Contains(config.Image, "@") {
+		// Already pinned, by a previous incarnation of the container or
+		// with a digest.
+		return nil
+	}
+
+	images := c.Images(false)
+	for _, image := range images {
+		if image.Id == config.Image || (len(config.Image) >= 12 && strings.HasPrefix(image.Id, config.Image)) {
+			// Already an image ID.
+			return nil
+		}
+	}
+
+	name := pinnedImageKey(config.Image)
+
+	c.pinnedImagesLock.Lock()
+	defer c.pinnedImagesLock.Unlock()
+
+	if id, ok := c.pinnedImages[name]; ok {
+		for _, image := range images {
+			if image.Id == id {
+				config.PinImage(id)
+				return nil
+			}
+		}
+		// The image was removed from every engine since.
+	}
+
+	newest := newestImage(images, config.Image)
+	if newest == nil {
+		engine, err := pullOn()
+		if err != nil {
+			return err
+		}
+		if err := engine.Pull(config.Image, nil); err != nil {
+			return err
+		}
+		if newest = newestImage(engine.Images(false), config.Image); newest == nil {
+			return fmt.Errorf("image %s pulled on %s not found", config.Image, engine.Name)
+		}
+	}
+
+	c.pinnedImages[name] = newest.Id
+	config.PinImage(newest.Id)
+	return nil
+}
+
+// unpinImage forgets the image ID an image name was resolved to, so that the
+// next container created with the name is pinned to the most recent image.
+func (c *Cluster) unpinImage(name string) {
+	c.pinnedImagesLock.Lock()
+	delete(c.pinnedImages, pinnedImageKey(name))
+	c.pinnedImagesLock.Unlock()
+}
+
+// pinnedImageKey returns the name of an image with its tag, latest if none.
+func pinnedImageKey(name string) string {
+	if !strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		return name + ":latest"
+	}
+	return name
+}
+
+// newestImage returns the most recent of the images matching name.
+func newestImage(images []*cluster.Image, name string) *cluster.Image {
+	var newest *cluster.Image
+	for _, image := range images {
+		if image.Match(name, true) && (newest == nil || image.Created > newest.Created) {
+			newest = image
+		}
+	}
+	return newest
+}
+
 // RemoveImages removes all the images that match `name` from the cluster
 func (c *Cluster) RemoveImages(name string, force bool) ([]*dockerclient.ImageDelete, error) {
 	c.Lock()
@@ -296,32 +578,58 @@ func (c *Cluster) RemoveImages(name string, force bool) ([]*dockerclient.ImageDe
 }
 
 // Pull is exported
-func (c *Cluster) Pull(name string, authConfig *dockerclient.AuthConfig, callback func(where, status string, err error)) {
-	var wg sync.WaitGroup
+func (c *Cluster) Pull(name string, authConfig *dockerclient.AuthConfig, options *cluster.PullOptions, callback func(where string, message *cluster.ProgressMessage, err error)) {
+	if options == nil {
+		options = &cluster.PullOptions{}
+	}
+	engines, err := c.enginesMatching(options.Constraints)
+	if err != nil {
+		if callback != nil {
+			callback("", nil, err)
+		}
+		return
+	}
 
-	c.RLock()
-	for _, e := range c.engines {
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = c.pullConcurrency
+	}
+	if concurrency <= 0 {
+		concurrency = len(engines)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, concurrency)
+	)
+	for _, e := range engines {
 		wg.Add(1)
 
 		go func(engine *cluster.Engine) {
 			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
 
-			if callback != nil {
-				callback(engine.Name, "", nil)
+			progress := func(message *cluster.ProgressMessage) {
+				if callback != nil {
+					callback(engine.Name, message, nil)
+				}
 			}
-			err := engine.Pull(name, authConfig)
-			if callback != nil {
-				if err != nil {
-					callback(engine.Name, "", err)
-				} else {
-					callback(engine.Name, "downloaded", nil)
+			progress(&cluster.ProgressMessage{Status: fmt.Sprintf("Pulling %s...", name)})
+			if err := engine.PullWithProgress(name, authConfig, progress); err != nil {
+				if callback != nil {
+					callback(engine.Name, nil, err)
 				}
+				return
 			}
+			progress(&cluster.ProgressMessage{Status: fmt.Sprintf("Pulling %s... : downloaded", name)})
 		}(e)
 	}
-	c.RUnlock()
 
 	wg.Wait()
+
+	// The pulled image may be more recent than the one the name is pinned to.
+	c.unpinImage(name)
 }
 
 // Load image
@@ -456,6 +764,19 @@ func (c *Cluster) getIDFromName(name string) string {
 	return ""
 }
 
+func (c *Cluster) getIDFromSwarmID(swarmID string) string {
+	c.RLock()
+	defer c.RUnlock()
+	for _, e := range c.engines {
+		for _, c := range e.Containers() {
+			if c.Config.SwarmID() == swarmID {
+				return c.Id
+			}
+		}
+	}
+	return ""
+}
+
 // Container returns the container with IDOrName in the cluster
 func (c *Cluster) Container(IDOrName string) *cluster.Container {
 	// Abort immediately if the name is empty.
@@ -483,24 +804,36 @@ func (c *Cluster) Volumes() []*cluster.Volume {
 	return out
 }
 
-// Volume returns the volume name in the cluster
+// Volume returns the volume name in the cluster, prefixed or not with the
+// name of its node.
 func (c *Cluster) Volume(name string) *cluster.Volume {
-	// Abort immediately if the name is empty.
-	if len(name) == 0 {
-		return nil
+	return cluster.Volumes(c.Volumes()).Get(name)
+}
+
+// CreateVolume creates a volume on the node selected by the scheduler for its
+// placement.
+func (c *Cluster) CreateVolume(request *cluster.VolumeCreateRequest) (*cluster.Volume, error) {
+	config := request.Placement()
+
+	c.scheduler.Lock()
+	n, err := c.scheduler.SelectNodeForContainer(c.listNodes(), config)
+	c.scheduler.Unlock()
+	if err != nil {
+		return nil, err
 	}
 
 	c.RLock()
-	defer c.RUnlock()
-
-	for _, e := range c.engines {
-		for _, v := range e.Volumes() {
-			if v.Name == name {
-				return v
-			}
-		}
+	engine, ok := c.engines[n.ID]
+	c.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unable to find engine %s", n.Name)
 	}
-	return nil
+	return engine.CreateVolume(request)
+}
+
+// RemoveVolume removes a volume from its engine.
+func (c *Cluster) RemoveVolume(volume *cluster.Volume) error {
+	return volume.Engine.RemoveVolume(volume.Name)
 }
 
 // listNodes returns all the engines in the cluster.
@@ -528,6 +861,42 @@ func (c *Cluster) listEngines() []*cluster.Engine {
 	return out
 }
 
+// Nodes returns the description of every node of the cluster, sorted by name.
+func (c *Cluster) Nodes() []*cluster.NodeInfo {
+	engines := c.listEngines()
+	sort.Sort(cluster.EngineSorter(engines))
+
+	out := make([]*cluster.NodeInfo, 0, len(engines))
+	for _, engine := range engines {
+		out = append(out, cluster.NewNodeInfo(engine))
+	}
+	return out
+}
+
+// Node returns the node matching IDOrName, either by ID or by name.
+func (c *Cluster) Node(IDOrName string) *cluster.NodeInfo {
+	if engine := c.getEngine(IDOrName); engine != nil {
+		return cluster.NewNodeInfo(engine)
+	}
+	return nil
+}
+
+// getEngine returns the engine matching IDOrName, either by ID or by name.
+func (c *Cluster) getEngine(IDOrName string) *cluster.Engine {
+	engines := c.listEngines()
+	for _, engine := range engines {
+		if engine.ID == IDOrName {
+			return engine
+		}
+	}
+	for _, engine := range engines {
+		if engine.Name == IDOrName {
+			return engine
+		}
+	}
+	return nil
+}
+
 // TotalMemory return the total memory of the cluster
 func (c *Cluster) TotalMemory() int64 {
 	var totalMemory int64
@@ -560,8 +929,15 @@ func (c *Cluster) Info() [][]string {
 	for _, engine := range engines {
 		info = append(info, []string{engine.Name, engine.Addr})
 		info = append(info, []string{" └ Containers", fmt.Sprintf("%d", len(engine.Containers()))})
-		info = append(info, []string{" └ Reserved CPUs", fmt.Sprintf("%d / %d", engine.UsedCpus(), engine.TotalCpus())})
-		info = append(info, []string{" └ Reserved Memory", fmt.Sprintf("%s / %s", units.BytesSize(float64(engine.UsedMemory())), units.BytesSize(float64(engine.TotalMemory())))})
+		info = append(info, []string{" └ Reserved CPUs", fmt.Sprintf("%s / %s", cluster.FormatCpus(engine.UsedMilliCpus()), cluster.FormatCpus(engine.AllocatableMilliCpus()))})
+		info = append(info, []string{" └ Reserved Memory", fmt.Sprintf("%s / %s", units.BytesSize(float64(engine.UsedMemory())), units.BytesSize(float64(engine.AllocatableMemory())))})
+		info = append(info, []string{" └ Limited CPUs", cluster.FormatCpus(engine.LimitedMilliCpus())})
+		info = append(info, []string{" └ Limited Memory", units.BytesSize(float64(engine.LimitedMemory()))})
+		if engine.DiskTotal > 0 {
+			info = append(info, []string{" └ Disk", fmt.Sprintf("%s / %s", units.HumanSize(float64(engine.DiskUsed)), units.HumanSize(float64(engine.DiskTotal)))})
+		}
+		info = append(info, []string{" └ Allocatable CPUs", fmt.Sprintf("%s / %s", cluster.FormatCpus(engine.AllocatableMilliCpus()), cluster.FormatCpus(engine.TotalMilliCpus()))})
+		info = append(info, []string{" └ Allocatable Memory", fmt.Sprintf("%s / %s", units.BytesSize(float64(engine.AllocatableMemory())), units.BytesSize(float64(engine.TotalMemory())))})
 		labels := make([]string, 0, len(engine.Labels))
 		for k, v := range engine.Labels {
 			labels = append(labels, k+"="+v)
@@ -600,15 +976,20 @@ func (c *Cluster) RenameContainer(container *cluster.Container, newName string)
 	return err
 }
 
-// BuildImage build an image
-func (c *Cluster) BuildImage(buildImage *dockerclient.BuildImage, out io.Writer) error {
+// BuildImage build an image on a node matching the placement of the
+// options, and then distributes it if asked to.
+func (c *Cluster) BuildImage(buildImage *dockerclient.BuildImage, options *cluster.BuildOptions, out io.Writer) error {
+	if options == nil {
+		options = &cluster.BuildOptions{}
+	}
+	if options.Distribute && buildImage.RepoName == "" {
+		return errors.New("Only tagged images can be distributed")
+	}
+
 	c.scheduler.Lock()
 
 	// get an engine
-	config := &cluster.ContainerConfig{dockerclient.ContainerConfig{
-		CpuShares: buildImage.CpuShares,
-		Memory:    buildImage.Memory,
-	}}
+	config := cluster.BuildConfig(buildImage, options)
 	n, err := c.scheduler.SelectNodeForContainer(c.listNodes(), config)
 	c.scheduler.Unlock()
 	if err != nil {
@@ -625,9 +1006,36 @@ func (c *Cluster) BuildImage(buildImage *dockerclient.BuildImage, out io.Writer)
 	}
 
 	c.engines[n.ID].RefreshImages()
+
+	if options.Distribute && c.engines[n.ID].Image(buildImage.RepoName) != nil {
+		return c.distributeBuild(buildImage.RepoName, options, out)
+	}
 	return nil
 }
 
+// distributeBuild distributes the image built and reports it in the build
+// output.
+func (c *Cluster) distributeBuild(name string, options *cluster.BuildOptions, out io.Writer) error {
+	var (
+		mu  sync.Mutex
+		enc = json.NewEncoder(out)
+	)
+	callback := func(where, status string, err error) {
+		if err != nil {
+			status = err.Error()
+		}
+		mu.Lock()
+		enc.Encode(&buildMessage{Stream: fmt.Sprintf("Distributing %s... : %s: %s\n", name, where, status)})
+		mu.Unlock()
+	}
+	return c.DistributeImage(name, options.DistributeConstraints, options.Concurrency, callback)
+}
+
+// buildMessage is a line of the build output.
+type buildMessage struct {
+	Stream string `json:"stream"`
+}
+
 // TagImage tag an image
 func (c *Cluster) TagImage(IDOrName string, repo string, tag string, force bool) error {
 	c.RLock()
@@ -2,11 +2,18 @@ package swarm
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
 	"github.com/samalba/dockerclient"
 	"github.com/samalba/dockerclient/mockclient"
 	"github.com/stretchr/testify/assert"
@@ -53,6 +60,55 @@ func createEngine(t *testing.T, ID string, containers ...*cluster.Container) *cl
 	return engine
 }
 
+// newMockCluster returns a primary cluster without engines, placing
+// containers with the spread strategy and the constraint filter.
+func newMockCluster() *Cluster {
+	return &Cluster{
+		engines:      make(map[string]*cluster.Engine),
+		scheduler:    scheduler.New(&strategy.SpreadPlacementStrategy{}, []filter.Filter{&filter.ConstraintFilter{}}),
+		restarts:     make(map[string]*restartTracker),
+		nodeLabels:   make(map[string]map[string]string),
+		pinnedImages: make(map[string]string),
+		primary:      true,
+	}
+}
+
+// addMockEngine connects the engine at `addr` to a mock client and adds it to
+// the cluster. The engine is named and identified by `id`, labeled with
+// group=<first letter of id>, and lists `images` and `containers`, which are
+// inspected as exited busybox containers.
+func addMockEngine(t *testing.T, c *Cluster, addr, id string, images []*dockerclient.Image, containers ...dockerclient.Container) (*cluster.Engine, *mockclient.MockClient) {
+	engine := cluster.NewEngine(addr, 0)
+
+	info := *mockInfo
+	info.ID = id
+	info.Name = id
+	info.Labels = []string{"group=" + id[:1]}
+
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(&info, nil)
+	client.On("Version").Return(mockVersion, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	client.On("ListContainers", true, false, "").Return(containers, nil).Once()
+	client.On("ListImages", mock.Anything).Return(images, nil)
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	for _, container := range containers {
+		client.On("InspectContainer", container.Id).Return(&dockerclient.ContainerInfo{
+			Id: container.Id,
+			Config: &dockerclient.ContainerConfig{
+				Image:  "busybox",
+				Labels: container.Labels,
+			},
+			State:      &dockerclient.State{ExitCode: 1},
+			HostConfig: &dockerclient.HostConfig{},
+		}, nil)
+	}
+
+	assert.NoError(t, engine.ConnectWithClient(client))
+	c.engines[engine.ID] = engine
+	return engine, client
+}
+
 func TestContainerLookup(t *testing.T) {
 	c := &Cluster{
 		engines: make(map[string]*cluster.Engine),
@@ -111,6 +167,51 @@ func TestContainerLookup(t *testing.T) {
 	assert.Equal(t, cc.Id, "container2-id")
 }
 
+func TestNodeLookup(t *testing.T) {
+	c := &Cluster{
+		engines: make(map[string]*cluster.Engine),
+	}
+	for _, name := range []string{"node-10", "node-2"} {
+		n := createEngine(t, name)
+		n.ID = name + "-id"
+		c.engines[n.ID] = n
+	}
+
+	nodes := c.Nodes()
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "node-2", nodes[0].Name)
+	assert.Equal(t, "node-10", nodes[1].Name)
+
+	assert.Nil(t, c.Node("invalid"))
+	assert.Equal(t, "node-2", c.Node("node-2-id").Name)
+	assert.Equal(t, "node-10-id", c.Node("node-10").ID)
+}
+
+func TestCreateContainerSwarmIDConflict(t *testing.T) {
+	c := &Cluster{
+		engines:   make(map[string]*cluster.Engine),
+		scheduler: scheduler.New(&strategy.SpreadPlacementStrategy{}, nil),
+	}
+	container := &cluster.Container{
+		Container: dockerclient.Container{Id: "container-id"},
+		Config: cluster.BuildContainerConfig(dockerclient.ContainerConfig{
+			Labels: map[string]string{
+				"com.docker.swarm.id": "swarm-id",
+			},
+		}),
+	}
+	n := createEngine(t, "test-engine", container)
+	c.engines[n.ID] = n
+
+	// A Swarm ID can't be reused while its container still exists.
+	config := cluster.BuildContainerConfig(dockerclient.ContainerConfig{})
+	config.SetSwarmID("swarm-id")
+	_, err := c.CreateContainer(config, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Conflict")
+	assert.Equal(t, config.SwarmID(), "swarm-id")
+}
+
 func TestImportImage(t *testing.T) {
 	// create cluster
 	c := &Cluster{
@@ -244,3 +345,189 @@ func TestTagImage(t *testing.T) {
 	assert.Nil(t, c.TagImage("busybox", "test_busybox", "latest", false))
 	assert.NotNil(t, c.TagImage("busybox_not_exists", "test_busybox", "latest", false))
 }
+
+func TestUpdateEngineID(t *testing.T) {
+	c := &Cluster{
+		engines: make(map[string]*cluster.Engine),
+	}
+	engine := createEngine(t, "old-id")
+	other := createEngine(t, "other-id")
+	c.engines[engine.ID] = engine
+	c.engines[other.ID] = other
+
+	// Docker was reinstalled at the same address.
+	engine.ID = "new-id"
+	c.Handle(&cluster.Event{Event: dockerclient.Event{Status: "engine_update"}, Engine: engine})
+
+	assert.Len(t, c.engines, 2)
+	assert.Nil(t, c.engines["old-id"])
+	assert.Equal(t, c.engines["new-id"], engine)
+	assert.Equal(t, c.engines["other-id"], other)
+
+	// The new ID is already used by another engine.
+	engine.ID = "other-id"
+	c.Handle(&cluster.Event{Event: dockerclient.Event{Status: "engine_update"}, Engine: engine})
+
+	assert.Len(t, c.engines, 1)
+	assert.Equal(t, c.engines["other-id"], other)
+}
+
+type countingHandler struct {
+	count int
+}
+
+func (h *countingHandler) Handle(e *cluster.Event) error {
+	h.count++
+	return nil
+}
+
+func TestEventHandlersFanOut(t *testing.T) {
+	c := &Cluster{
+		engines: make(map[string]*cluster.Engine),
+	}
+	h1, h2 := &countingHandler{}, &countingHandler{}
+	assert.NoError(t, c.RegisterEventHandler(h1))
+	assert.NoError(t, c.RegisterEventHandler(h2))
+
+	c.Handle(&cluster.Event{Event: dockerclient.Event{Status: "start"}, Engine: createEngine(t, "test-engine")})
+	assert.Equal(t, h1.count, 1)
+	assert.Equal(t, h2.count, 1)
+}
+
+func TestPinImage(t *testing.T) {
+	c := &Cluster{
+		engines:      make(map[string]*cluster.Engine),
+		pinnedImages: make(map[string]string),
+	}
+
+	// Two engines pulled busybox:latest at different times.
+	var (
+		engines []*cluster.Engine
+		clients []*mockclient.MockClient
+	)
+	for i, image := range []*dockerclient.Image{
+		{Id: "1111111111111111", Created: 1, RepoTags: []string{"busybox:latest"}},
+		{Id: "2222222222222222", Created: 2, RepoTags: []string{"busybox:latest"}},
+	} {
+		id := fmt.Sprintf("engine-%d", i)
+		engine := cluster.NewEngine(id, 0)
+		client := mockclient.NewMockClient()
+		client.On("Info").Return(&dockerclient.Info{ID: id, Name: id, NCPU: 1, MemTotal: 1024}, nil)
+		client.On("Version").Return(mockVersion, nil)
+		client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+		client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
+		client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{image}, nil).Once()
+		client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+		assert.NoError(t, engine.ConnectWithClient(client))
+		c.engines[engine.ID] = engine
+		engines = append(engines, engine)
+		clients = append(clients, client)
+	}
+	noPull := func() (*cluster.Engine, error) {
+		t.Fatal("unexpected pull")
+		return nil, nil
+	}
+
+	// The most recent image is pinned.
+	config := cluster.BuildContainerConfig(dockerclient.ContainerConfig{Image: "busybox"})
+	assert.NoError(t, c.pinImage(config, noPull))
+	assert.Equal(t, "2222222222222222", config.Image)
+	assert.Equal(t, "busybox", config.ImageName())
+
+	// Pinning is kept when the container is recreated.
+	assert.NoError(t, c.pinImage(config, noPull))
+	assert.Equal(t, "2222222222222222", config.Image)
+	assert.Equal(t, "busybox", config.ImageName())
+
+	// The name is only resolved once: a more recent image doesn't change
+	// the ID the next containers are pinned to...
+	clients[0].On("ListImages", mock.Anything).Return([]*dockerclient.Image{{Id: "3333333333333333", Created: 3, RepoTags: []string{"busybox:latest"}}}, nil).Once()
+	assert.NoError(t, engines[0].RefreshImages())
+	config = cluster.BuildContainerConfig(dockerclient.ContainerConfig{Image: "busybox:latest"})
+	assert.NoError(t, c.pinImage(config, noPull))
+	assert.Equal(t, "2222222222222222", config.Image)
+
+	// ...Until the image is pulled through the cluster.
+	c.unpinImage("busybox")
+	config = cluster.BuildContainerConfig(dockerclient.ContainerConfig{Image: "busybox"})
+	assert.NoError(t, c.pinImage(config, noPull))
+	assert.Equal(t, "3333333333333333", config.Image)
+
+	// IDs aren't pinned.
+	config = cluster.BuildContainerConfig(dockerclient.ContainerConfig{Image: "222222222222"})
+	assert.NoError(t, c.pinImage(config, noPull))
+	assert.Equal(t, "222222222222", config.Image)
+	assert.Equal(t, "222222222222", config.ImageName())
+
+	// An image no engine has is pulled on a single engine, and every
+	// container is pinned to the pulled image.
+	clients[1].On("PullImage", "redis:latest", mock.Anything).Return(nil).Once()
+	clients[1].On("ListImages", mock.Anything).Return([]*dockerclient.Image{{Id: "4444444444444444", Created: 4, RepoTags: []string{"redis:latest"}}}, nil).Once()
+	pulls := 0
+	pullOn := func() (*cluster.Engine, error) {
+		pulls++
+		return engines[1], nil
+	}
+	for i := 0; i < 2; i++ {
+		config = cluster.BuildContainerConfig(dockerclient.ContainerConfig{Image: "redis"})
+		assert.NoError(t, c.pinImage(config, pullOn))
+		assert.Equal(t, "4444444444444444", config.Image)
+		assert.Equal(t, "redis", config.ImageName())
+	}
+	assert.Equal(t, 1, pulls)
+	clients[1].Mock.AssertExpectations(t)
+
+	// Failing to pull the image fails the creation.
+	clients[1].On("PullImage", "unknown:latest", mock.Anything).Return(errors.New("not found")).Once()
+	config = cluster.BuildContainerConfig(dockerclient.ContainerConfig{Image: "unknown"})
+	assert.Error(t, c.pinImage(config, pullOn))
+}
+
+func TestBuildImage(t *testing.T) {
+	c := newMockCluster()
+	addMockEngine(t, c, "1.1.1.1:1234", "aa", []*dockerclient.Image{})
+	_, client := addMockEngine(t, c, "2.2.2.2:1234", "bb", []*dockerclient.Image{})
+
+	// The build runs on the node matching the constraints.
+	buildImage := &dockerclient.BuildImage{RepoName: "app"}
+	client.On("BuildImage", buildImage).Return(ioutil.NopCloser(bytes.NewBufferString("{\"stream\":\"Successfully built\"}\n")), nil).Once()
+	out := bytes.NewBuffer(nil)
+	assert.NoError(t, c.BuildImage(buildImage, &cluster.BuildOptions{Constraints: []string{"group==b"}}, out))
+	assert.Equal(t, "{\"stream\":\"Successfully built\"}\n", out.String())
+	client.Mock.AssertExpectations(t)
+
+	assert.Error(t, c.BuildImage(buildImage, &cluster.BuildOptions{Constraints: []string{"group==c"}}, out))
+
+	// Only tagged images can be distributed.
+	assert.Error(t, c.BuildImage(&dockerclient.BuildImage{}, &cluster.BuildOptions{Distribute: true}, out))
+}
+
+func TestCreateVolume(t *testing.T) {
+	c := newMockCluster()
+
+	created := make(map[string]int)
+	for _, id := range []string{"aa", "bb"} {
+		id := id
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			created[id]++
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"Name":"data","Driver":"local"}`)
+		}))
+		defer server.Close()
+		addMockEngine(t, c, server.Listener.Addr().String(), id, []*dockerclient.Image{})
+	}
+
+	// The name of the node selects it.
+	volume, err := c.CreateVolume(&cluster.VolumeCreateRequest{Name: "bb/data"})
+	assert.NoError(t, err)
+	assert.Equal(t, volume.Engine.Name, "bb")
+	assert.Equal(t, created, map[string]int{"bb": 1})
+
+	volume, err = c.CreateVolume(&cluster.VolumeCreateRequest{Name: "data", DriverOpts: map[string]string{"constraint:group": "=a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, volume.Engine.Name, "aa")
+	assert.Equal(t, created, map[string]int{"aa": 1, "bb": 1})
+
+	_, err = c.CreateVolume(&cluster.VolumeCreateRequest{Name: "cc/data"})
+	assert.Error(t, err)
+}
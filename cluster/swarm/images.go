@@ -0,0 +1,141 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/units"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/samalba/dockerclient"
+)
+
+// Interval between two progress reports of an image transfer.
+const progressInterval = time.Second
+
+var errNoMatchingNode = errors.New("No node matching the constraints")
+
+// enginesMatching returns the healthy engines matching the constraints, all
+// of them if there are none.
+func (c *Cluster) enginesMatching(constraints []string) ([]*cluster.Engine, error) {
+	nodes := c.listNodes()
+	if len(constraints) > 0 {
+		env := make([]string, 0, len(constraints))
+		for _, constraint := range constraints {
+			env = append(env, "constraint:"+constraint)
+		}
+		config := cluster.BuildContainerConfig(dockerclient.ContainerConfig{Env: env})
+
+		var err error
+		if nodes, err = (&filter.ConstraintFilter{}).Filter(config, nodes); err != nil {
+			return nil, err
+		}
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	engines := []*cluster.Engine{}
+	for _, n := range nodes {
+		if engine, ok := c.engines[n.ID]; ok && engine.IsHealthy() {
+			engines = append(engines, engine)
+		}
+	}
+	if len(engines) == 0 {
+		return nil, errNoMatchingNode
+	}
+	return engines, nil
+}
+
+// DistributeImage copies the image `name` from an engine which has it to the
+// engines matching `constraints` which don't, without a registry. At most
+// `concurrency` engines load the image at the same time.
+func (c *Cluster) DistributeImage(name string, constraints []string, concurrency int, callback func(where, status string, err error)) error {
+	var source *cluster.Image
+	for _, engine := range c.listEngines() {
+		if !engine.IsHealthy() {
+			continue
+		}
+		if image := engine.Image(name); image != nil {
+			source = image
+			break
+		}
+	}
+	if source == nil {
+		return fmt.Errorf("No such image: %s", name)
+	}
+
+	targets, err := c.enginesMatching(constraints)
+	if err != nil {
+		return err
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, concurrency)
+	)
+	for _, engine := range targets {
+		if image := engine.Image(name); image != nil && image.Id == source.Id {
+			callback(engine.Name, "Already exists", nil)
+			continue
+		}
+
+		wg.Add(1)
+		callback(engine.Name, "Waiting", nil)
+		go func(engine *cluster.Engine) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if err := copyImage(source, name, engine, callback); err != nil {
+				callback(engine.Name, "", err)
+				return
+			}
+			callback(engine.Name, fmt.Sprintf("Loaded from %s", source.Engine.Name), nil)
+		}(engine)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// copyImage streams the image from the engine of source into target.
+func copyImage(source *cluster.Image, name string, target *cluster.Engine, callback func(where, status string, err error)) error {
+	reader, err := source.Engine.Save([]string{name})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return target.Load(&progressReader{
+		reader: reader,
+		report: func(n int64) {
+			callback(target.Name, fmt.Sprintf("Copying from %s: %s", source.Engine.Name, units.HumanSize(float64(n))), nil)
+		},
+	})
+}
+
+// progressReader reports the number of bytes read at most every
+// progressInterval.
+type progressReader struct {
+	reader     io.Reader
+	report     func(n int64)
+	n          int64
+	lastReport time.Time
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	if now := time.Now(); now.Sub(r.lastReport) >= progressInterval {
+		r.lastReport = now
+		r.report(r.n)
+	}
+	return n, err
+}
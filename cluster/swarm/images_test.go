@@ -0,0 +1,123 @@
+package swarm
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDistributeImage(t *testing.T) {
+	// Either node with the image can be the source.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/images/get", r.URL.Path)
+		assert.Equal(t, []string{"app"}, r.URL.Query()["names"])
+		io.WriteString(w, "tarball")
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	copyServer := httptest.NewServer(handler)
+	defer copyServer.Close()
+
+	c := newMockCluster()
+	image := &dockerclient.Image{Id: "1234567890", RepoTags: []string{"app:latest"}}
+	addMockEngine(t, c, server.Listener.Addr().String(), "a-source", []*dockerclient.Image{image})
+	addMockEngine(t, c, copyServer.Listener.Addr().String(), "b-copy", []*dockerclient.Image{image})
+	_, target := addMockEngine(t, c, "a-target:2375", "a-target", []*dockerclient.Image{})
+	addMockEngine(t, c, "c-other:2375", "c-other", []*dockerclient.Image{})
+
+	target.On("LoadImage", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		data, err := ioutil.ReadAll(args.Get(0).(io.Reader))
+		assert.NoError(t, err)
+		assert.Equal(t, "tarball", string(data))
+	}).Once()
+
+	var (
+		mu     sync.Mutex
+		status = map[string]string{}
+	)
+	callback := func(where, s string, err error) {
+		assert.NoError(t, err)
+		mu.Lock()
+		status[where] = s
+		mu.Unlock()
+	}
+
+	// Only the nodes of group "a" and "b" are targeted.
+	assert.NoError(t, c.DistributeImage("app", []string{"group!=c"}, 2, callback))
+	keys := []string{}
+	for k := range status {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a-source", "a-target", "b-copy"}, keys)
+	assert.Equal(t, "Already exists", status["a-source"])
+	assert.Equal(t, "Already exists", status["b-copy"])
+	assert.Contains(t, []string{"Loaded from a-source", "Loaded from b-copy"}, status["a-target"])
+	target.Mock.AssertExpectations(t)
+
+	assert.Error(t, c.DistributeImage("missing", nil, 2, callback))
+	assert.Error(t, c.DistributeImage("app", []string{"group==z"}, 2, callback))
+}
+
+func TestPullConstraintsAndConcurrency(t *testing.T) {
+	var (
+		mu               sync.Mutex
+		running, maximum int
+		pulled           []string
+	)
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			running++
+			if running > maximum {
+				maximum = running
+			}
+			pulled = append(pulled, name)
+			mu.Unlock()
+
+			io.WriteString(w, `{"status":"Downloading","progressDetail":{"current":1,"total":2},"id":"layer"}`)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}
+	}
+
+	c := newMockCluster()
+	for _, name := range []string{"a-1", "a-2", "a-3", "b-1"} {
+		server := httptest.NewServer(handler(name))
+		defer server.Close()
+		addMockEngine(t, c, server.Listener.Addr().String(), name, []*dockerclient.Image{})
+	}
+
+	var messages []string
+	callback := func(where string, message *cluster.ProgressMessage, err error) {
+		assert.NoError(t, err)
+		mu.Lock()
+		messages = append(messages, where+" "+message.ID+" "+message.Status)
+		mu.Unlock()
+	}
+	c.Pull("busybox", nil, &cluster.PullOptions{Constraints: []string{"group==a"}, Concurrency: 1}, callback)
+
+	sort.Strings(pulled)
+	assert.Equal(t, []string{"a-1", "a-2", "a-3"}, pulled)
+	assert.Equal(t, 1, maximum)
+	assert.Len(t, messages, 9)
+	assert.Contains(t, messages, "a-2 layer Downloading")
+	assert.Contains(t, messages, "a-3  Pulling busybox... : downloaded")
+
+	var pullErr error
+	c.Pull("busybox", nil, &cluster.PullOptions{Constraints: []string{"group==z"}}, func(where string, message *cluster.ProgressMessage, err error) {
+		pullErr = err
+	})
+	assert.Error(t, pullErr)
+}
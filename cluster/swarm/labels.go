@@ -0,0 +1,149 @@
+package swarm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+	"github.com/docker/swarm/cluster"
+)
+
+const (
+	// Path of the manager labels in the KV store, relative to the discovery
+	// prefix. There is one key per engine ID.
+	nodeLabelsPath = "docker/swarm/labels"
+
+	// Delay before watching the manager labels again after an error.
+	nodeLabelsRetryDelay = 10 * time.Second
+
+	// Number of times an update of the manager labels is retried when they
+	// are changed concurrently.
+	nodeLabelsUpdateRetries = 5
+)
+
+var errNodeLabelsNotSupported = errors.New("node labels are only supported with consul, etcd and zookeeper discovery")
+
+// UpdateNodeLabels adds or updates the manager labels of a node and removes
+// the ones listed in removed. The labels are saved in the KV store so that
+// every manager sees them. The update is atomic: it is retried when another
+// manager changed the labels of the node in the meantime.
+func (c *Cluster) UpdateNodeLabels(IDOrName string, labels map[string]string, removed []string) error {
+	if c.nodeLabelsStore == nil {
+		return errNodeLabelsNotSupported
+	}
+
+	engine := c.getEngine(IDOrName)
+	if engine == nil {
+		return fmt.Errorf("No such node: %s", IDOrName)
+	}
+
+	key := path.Join(c.nodeLabelsPath, engine.ID)
+	for i := 0; ; i++ {
+		nodeLabels, previous, err := c.loadNodeLabels(key)
+		if err != nil {
+			return err
+		}
+		for k, v := range labels {
+			nodeLabels[k] = v
+		}
+		for _, k := range removed {
+			delete(nodeLabels, k)
+		}
+
+		data, err := json.Marshal(nodeLabels)
+		if err != nil {
+			return err
+		}
+		if _, _, err = c.nodeLabelsStore.AtomicPut(key, data, previous, nil); err == nil {
+			// Apply the labels right away rather than waiting for the watch.
+			c.setNodeLabels(engine.ID, nodeLabels)
+			return nil
+		}
+		// Creating the key fails with a backend specific error when another
+		// manager created it first, so any error is retried in that case.
+		if (err != store.ErrKeyModified && previous != nil) || i >= nodeLabelsUpdateRetries {
+			return err
+		}
+		log.WithField("node", engine.Name).Debugf("Node labels changed concurrently, retrying: %v", err)
+	}
+}
+
+// loadNodeLabels returns the manager labels saved in the KV store at key,
+// along with the KV pair to compare to when saving them. The pair is nil if
+// the node has no labels yet.
+func (c *Cluster) loadNodeLabels(key string) (map[string]string, *store.KVPair, error) {
+	labels := make(map[string]string)
+	pair, err := c.nodeLabelsStore.Get(key)
+	if err == store.ErrKeyNotFound {
+		return labels, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(pair.Value, &labels); err != nil {
+		return nil, nil, err
+	}
+	return labels, pair, nil
+}
+
+// setNodeLabels records the manager labels of an engine, which may not be
+// registered yet, and applies them.
+func (c *Cluster) setNodeLabels(ID string, labels map[string]string) {
+	c.Lock()
+	c.nodeLabels[ID] = labels
+	engine := c.engines[ID]
+	c.Unlock()
+
+	if engine != nil {
+		engine.SetNodeLabels(labels)
+	}
+}
+
+// watchNodeLabels applies the manager labels saved in the KV store, by this
+// manager or any other one, as they change.
+func (c *Cluster) watchNodeLabels() {
+	for {
+		watchCh, err := c.nodeLabelsStore.WatchTree(c.nodeLabelsPath, nil)
+		if err != nil {
+			// The directory doesn't exist until a label is set.
+			if err != store.ErrKeyNotFound {
+				log.Errorf("Unable to watch the node labels: %v", err)
+			}
+		} else {
+			for pairs := range watchCh {
+				c.applyNodeLabels(pairs)
+			}
+		}
+		time.Sleep(nodeLabelsRetryDelay)
+	}
+}
+
+// applyNodeLabels replaces the manager labels of every engine with the ones
+// of the KV pairs.
+func (c *Cluster) applyNodeLabels(pairs []*store.KVPair) {
+	all := make(map[string]map[string]string)
+	for _, pair := range pairs {
+		labels := make(map[string]string)
+		if err := json.Unmarshal(pair.Value, &labels); err != nil {
+			log.Errorf("Ignoring invalid labels of node %s: %v", path.Base(pair.Key), err)
+			continue
+		}
+		all[path.Base(pair.Key)] = labels
+	}
+
+	c.Lock()
+	c.nodeLabels = all
+	engines := make([]*cluster.Engine, 0, len(c.engines))
+	for _, engine := range c.engines {
+		engines = append(engines, engine)
+	}
+	c.Unlock()
+
+	for _, engine := range engines {
+		engine.SetNodeLabels(all[engine.ID])
+	}
+}
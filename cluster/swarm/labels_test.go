@@ -0,0 +1,90 @@
+package swarm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/libkv/store"
+	libkvmock "github.com/docker/libkv/store/mock"
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUpdateNodeLabels(t *testing.T) {
+	kv, err := libkvmock.New([]string{"127.0.0.1"}, nil)
+	assert.NoError(t, err)
+	storeMock := kv.(*libkvmock.Mock)
+
+	c := &Cluster{
+		engines:    make(map[string]*cluster.Engine),
+		nodeLabels: make(map[string]map[string]string),
+	}
+	engine := createEngine(t, "test-engine")
+	c.engines[engine.ID] = engine
+
+	// Without a KV store, labels are not supported.
+	assert.Equal(t, errNodeLabelsNotSupported, c.UpdateNodeLabels("test-engine", map[string]string{"zone": "eu"}, nil))
+
+	c.nodeLabelsStore = kv
+	c.nodeLabelsPath = "prefix/" + nodeLabelsPath
+
+	assert.Error(t, c.UpdateNodeLabels("invalid", map[string]string{"zone": "eu"}, nil))
+
+	// The labels of the node are created.
+	key := "prefix/" + nodeLabelsPath + "/test-engine"
+	storeMock.On("Get", key).Return((*store.KVPair)(nil), store.ErrKeyNotFound).Once()
+	storeMock.On("AtomicPut", key, []byte(`{"rack":"1","zone":"eu"}`), (*store.KVPair)(nil), mock.Anything).Return(true, (*store.KVPair)(nil), nil).Once()
+
+	assert.NoError(t, c.UpdateNodeLabels("test-engine", map[string]string{"zone": "eu", "rack": "1"}, nil))
+	assert.Equal(t, "eu", engine.Labels[cluster.NodeLabelPrefix+"zone"])
+	assert.Equal(t, "1", engine.Labels[cluster.NodeLabelPrefix+"rack"])
+
+	// Another manager adds a label in the meantime: the update is retried
+	// without losing it.
+	first := &store.KVPair{Key: key, Value: []byte(`{"rack":"1","zone":"eu"}`), LastIndex: 1}
+	second := &store.KVPair{Key: key, Value: []byte(`{"disk":"ssd","rack":"1","zone":"eu"}`), LastIndex: 2}
+	storeMock.On("Get", key).Return(first, nil).Once()
+	storeMock.On("AtomicPut", key, mock.Anything, first, mock.Anything).Return(false, (*store.KVPair)(nil), store.ErrKeyModified).Once()
+	storeMock.On("Get", key).Return(second, nil).Once()
+	var saved []byte
+	storeMock.On("AtomicPut", key, mock.Anything, second, mock.Anything).Return(true, (*store.KVPair)(nil), nil).Run(func(args mock.Arguments) {
+		saved = args.Get(1).([]byte)
+	}).Once()
+
+	assert.NoError(t, c.UpdateNodeLabels("test-engine", map[string]string{"zone": "us"}, []string{"rack"}))
+	assert.Equal(t, "us", engine.Labels[cluster.NodeLabelPrefix+"zone"])
+	assert.Equal(t, "ssd", engine.Labels[cluster.NodeLabelPrefix+"disk"])
+	_, exists := engine.Labels[cluster.NodeLabelPrefix+"rack"]
+	assert.False(t, exists)
+
+	labels := make(map[string]string)
+	assert.NoError(t, json.Unmarshal(saved, &labels))
+	assert.Equal(t, map[string]string{"zone": "us", "disk": "ssd"}, labels)
+
+	storeMock.AssertExpectations(t)
+}
+
+func TestApplyNodeLabels(t *testing.T) {
+	c := &Cluster{
+		engines:    make(map[string]*cluster.Engine),
+		nodeLabels: make(map[string]map[string]string),
+	}
+	engine1 := createEngine(t, "engine-1")
+	engine1.SetNodeLabels(map[string]string{"zone": "eu"})
+	engine2 := createEngine(t, "engine-2")
+	c.engines[engine1.ID] = engine1
+	c.engines[engine2.ID] = engine2
+
+	c.applyNodeLabels([]*store.KVPair{
+		{Key: "prefix/" + nodeLabelsPath + "/engine-2", Value: []byte(`{"zone":"us"}`)},
+		{Key: "prefix/" + nodeLabelsPath + "/engine-3", Value: []byte(`{"zone":"asia"}`)},
+	})
+
+	// Labels removed from the store are removed from the engine.
+	_, exists := engine1.Labels[cluster.NodeLabelPrefix+"zone"]
+	assert.False(t, exists)
+	assert.Equal(t, "us", engine2.Labels[cluster.NodeLabelPrefix+"zone"])
+	// Labels of engines not registered yet are kept for later.
+	assert.Equal(t, map[string]string{"zone": "asia"}, c.nodeLabels["engine-3"])
+}
@@ -0,0 +1,74 @@
+package swarm
+
+import (
+	"sort"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/metrics"
+)
+
+// Collect returns the metrics of the engines and containers of the cluster.
+func (c *Cluster) Collect() []*metrics.Family {
+	engines := c.listEngines()
+	sort.Sort(cluster.EngineSorter(engines))
+
+	var (
+		healthy, unhealthy int
+		containers         = &metrics.Family{Name: "swarm_containers", Help: "Number of containers, by node and state.", Type: metrics.TypeGauge}
+		cpusReserved       = &metrics.Family{Name: "swarm_engine_cpus_reserved", Help: "Number of CPUs reserved by containers, by node.", Type: metrics.TypeGauge}
+		cpusTotal          = &metrics.Family{Name: "swarm_engine_cpus_total", Help: "Number of CPUs available to containers, overcommit included, by node.", Type: metrics.TypeGauge}
+		memoryReserved     = &metrics.Family{Name: "swarm_engine_memory_reserved_bytes", Help: "Memory reserved by containers, by node.", Type: metrics.TypeGauge}
+		memoryTotal        = &metrics.Family{Name: "swarm_engine_memory_total_bytes", Help: "Memory available to containers, overcommit included, by node.", Type: metrics.TypeGauge}
+	)
+
+	for _, engine := range engines {
+		if engine.IsHealthy() {
+			healthy++
+		} else {
+			unhealthy++
+		}
+
+		node := []metrics.Label{{Name: "node", Value: engine.Name}}
+		cpusReserved.Samples = append(cpusReserved.Samples, metrics.Sample{Labels: node, Value: float64(engine.UsedMilliCpus()) / 1000})
+		cpusTotal.Samples = append(cpusTotal.Samples, metrics.Sample{Labels: node, Value: float64(engine.TotalMilliCpus()) / 1000})
+		memoryReserved.Samples = append(memoryReserved.Samples, metrics.Sample{Labels: node, Value: float64(engine.UsedMemory())})
+		memoryTotal.Samples = append(memoryTotal.Samples, metrics.Sample{Labels: node, Value: float64(engine.TotalMemory())})
+
+		states := make(map[string]int)
+		for _, container := range engine.Containers() {
+			state := "unknown"
+			if container.Info.State != nil {
+				state = container.Info.State.StateString()
+			}
+			states[state]++
+		}
+		names := make([]string, 0, len(states))
+		for state := range states {
+			names = append(names, state)
+		}
+		sort.Strings(names)
+		for _, state := range names {
+			containers.Samples = append(containers.Samples, metrics.Sample{
+				Labels: []metrics.Label{{Name: "node", Value: engine.Name}, {Name: "state", Value: state}},
+				Value:  float64(states[state]),
+			})
+		}
+	}
+
+	return []*metrics.Family{
+		{
+			Name: "swarm_engines",
+			Help: "Number of engines, by health state.",
+			Type: metrics.TypeGauge,
+			Samples: []metrics.Sample{
+				{Labels: []metrics.Label{{Name: "state", Value: "healthy"}}, Value: float64(healthy)},
+				{Labels: []metrics.Label{{Name: "state", Value: "unhealthy"}}, Value: float64(unhealthy)},
+			},
+		},
+		containers,
+		cpusReserved,
+		cpusTotal,
+		memoryReserved,
+		memoryTotal,
+	}
+}
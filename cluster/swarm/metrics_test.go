@@ -0,0 +1,37 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/metrics"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollect(t *testing.T) {
+	c := &Cluster{
+		engines: make(map[string]*cluster.Engine),
+	}
+	c.engines["test-engine"] = createEngine(t, "test-engine",
+		&cluster.Container{Container: dockerclient.Container{Id: "c1"}, Config: cluster.BuildContainerConfig(dockerclient.ContainerConfig{}), Info: dockerclient.ContainerInfo{State: &dockerclient.State{Running: true}}},
+		&cluster.Container{Container: dockerclient.Container{Id: "c2"}, Config: cluster.BuildContainerConfig(dockerclient.ContainerConfig{}), Info: dockerclient.ContainerInfo{State: &dockerclient.State{Running: true}}},
+		&cluster.Container{Container: dockerclient.Container{Id: "c3"}, Config: cluster.BuildContainerConfig(dockerclient.ContainerConfig{})},
+	)
+
+	families := make(map[string]*metrics.Family)
+	for _, f := range c.Collect() {
+		families[f.Name] = f
+	}
+
+	node := metrics.Label{Name: "node", Value: "test-engine"}
+	assert.Equal(t, []metrics.Sample{
+		{Labels: []metrics.Label{{Name: "state", Value: "healthy"}}, Value: 1},
+		{Labels: []metrics.Label{{Name: "state", Value: "unhealthy"}}, Value: 0},
+	}, families["swarm_engines"].Samples)
+	assert.Equal(t, []metrics.Sample{
+		{Labels: []metrics.Label{node, {Name: "state", Value: "running"}}, Value: 2},
+		{Labels: []metrics.Label{node, {Name: "state", Value: "unknown"}}, Value: 1},
+	}, families["swarm_containers"].Samples)
+	assert.Len(t, families["swarm_engine_memory_total_bytes"].Samples, 1)
+}
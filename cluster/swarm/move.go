@@ -0,0 +1,75 @@
+package swarm
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+)
+
+// MoveContainer recreates a container on the node matching nodeIDOrName, or
+// on the node selected by the scheduler if it is empty, with the same config,
+// name and Swarm ID. The new container is started if the old one was running,
+// and the old one is then removed. The new container is removed instead if it
+// can't be started, leaving the old one untouched.
+func (c *Cluster) MoveContainer(container *cluster.Container, nodeIDOrName string) (*cluster.Container, error) {
+	p := &placement{excludedID: container.Engine.ID, replaced: container}
+	if nodeIDOrName != "" {
+		engine := c.getEngine(nodeIDOrName)
+		if engine == nil {
+			return nil, fmt.Errorf("No such node: %s", nodeIDOrName)
+		}
+		if engine == container.Engine {
+			return nil, fmt.Errorf("Container %s is already on %s", containerName(container), engine.Name)
+		}
+		p.nodeID = engine.ID
+	}
+
+	running := container.Info.State != nil && container.Info.State.Running
+	moved, err := c.replaceContainer(container, p, running)
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{"name": moved.Engine.Name, "id": moved.Id}).Infof("Container moved from %s", container.Engine.Name)
+	return moved, nil
+}
+
+// replaceContainer recreates a container on the engines the placement
+// accepts, starts the new container if `start` is set, and then removes the
+// old one. The new container is removed instead if it can't be started,
+// leaving the old one untouched.
+func (c *Cluster) replaceContainer(container *cluster.Container, p *placement, start bool) (*cluster.Container, error) {
+	name := containerName(container)
+
+	moved, err := c.createContainerWith(recreateConfig(container), name, p)
+	if err != nil {
+		return nil, err
+	}
+	if moved == nil {
+		return nil, fmt.Errorf("unable to recreate container %s", name)
+	}
+
+	if start {
+		if err := moved.Engine.StartContainer(moved); err != nil {
+			c.rollbackMove(moved)
+			return nil, fmt.Errorf("Unable to start container %s on %s: %v", name, moved.Engine.Name, err)
+		}
+	}
+
+	// The restart policy doesn't apply to the old container anymore.
+	swarmID := container.Config.SwarmID()
+	c.setReplacedID(swarmID, container.Id)
+	if err := c.RemoveContainer(container, true); err != nil {
+		c.setReplacedID(swarmID, "")
+		c.rollbackMove(moved)
+		return nil, fmt.Errorf("Unable to remove container %s from %s: %v", name, container.Engine.Name, err)
+	}
+	return moved, nil
+}
+
+// rollbackMove removes the container created by a move which failed.
+func (c *Cluster) rollbackMove(moved *cluster.Container) {
+	if err := c.RemoveContainer(moved, true); err != nil {
+		log.WithFields(log.Fields{"name": moved.Engine.Name, "id": moved.Id}).Errorf("Unable to remove container after a failed move: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package swarm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var moveLabels = map[string]string{
+	"com.docker.swarm.id":      "swarm-id",
+	"com.docker.swarm.restart": "always",
+}
+
+func moveCluster(t *testing.T) (*Cluster, *cluster.Container, []*mockclient.MockClient) {
+	c := newMockCluster()
+
+	engine1, client1 := addMockEngine(t, c, "engine1", "engine1", nil, dockerclient.Container{Id: "container-id", Names: []string{"/name"}, Labels: moveLabels})
+	_, client2 := addMockEngine(t, c, "engine2", "engine2", nil)
+
+	container := engine1.Containers()[0]
+	container.Info.State.Running = true
+
+	moved := dockerclient.Container{Id: "moved-id", Names: []string{"/name"}, Labels: moveLabels}
+	client2.On("CreateContainer", mock.Anything, "name").Return("moved-id", nil).Once()
+	client2.On("ListContainers", true, false, mock.Anything).Return([]dockerclient.Container{moved}, nil)
+	client2.On("InspectContainer", "moved-id").Return(&dockerclient.ContainerInfo{
+		Id:     "moved-id",
+		Config: &dockerclient.ContainerConfig{Image: "busybox", Labels: moveLabels},
+		State:  &dockerclient.State{Running: true},
+	}, nil)
+	return c, container, []*mockclient.MockClient{client1, client2}
+}
+
+func TestMoveContainer(t *testing.T) {
+	c, container, clients := moveCluster(t)
+	clients[1].On("StartContainer", "moved-id", mock.Anything).Return(nil).Once()
+	clients[0].On("RemoveContainer", "container-id", true, true).Return(nil).Once()
+
+	moved, err := c.MoveContainer(container, "engine2")
+	assert.NoError(t, err)
+	clients[0].AssertExpectations(t)
+	clients[1].AssertExpectations(t)
+
+	assert.Equal(t, moved.Engine.Name, "engine2")
+	assert.Equal(t, moved.Config.SwarmID(), "swarm-id")
+	assert.Len(t, c.engines["engine1"].Containers(), 0)
+	assert.Equal(t, c.Container("name"), moved)
+
+	// The old container being killed doesn't disable the restart policy.
+	c.handleRestartPolicy(&cluster.Event{Event: dockerclient.Event{Status: "kill", Id: "container-id"}, Engine: container.Engine, Container: container})
+	assert.False(t, c.restarts["swarm-id"].stopped)
+}
+
+func TestMoveContainerRollback(t *testing.T) {
+	c, container, clients := moveCluster(t)
+	clients[1].On("StartContainer", "moved-id", mock.Anything).Return(errors.New("port already allocated")).Once()
+	clients[1].On("RemoveContainer", "moved-id", true, true).Return(nil).Once()
+
+	// The scheduler selects the other node.
+	_, err := c.MoveContainer(container, "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "port already allocated")
+	clients[0].AssertExpectations(t)
+	clients[1].AssertExpectations(t)
+
+	// The old container is left untouched.
+	assert.Len(t, c.engines["engine2"].Containers(), 0)
+	assert.Equal(t, c.Container("name"), container)
+}
+
+func TestMoveContainerErrors(t *testing.T) {
+	c, container, _ := moveCluster(t)
+
+	_, err := c.MoveContainer(container, "engine1")
+	assert.Error(t, err)
+	_, err = c.MoveContainer(container, "engine3")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "No such node")
+}
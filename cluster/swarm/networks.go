@@ -0,0 +1,68 @@
+package swarm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/swarm/cluster"
+)
+
+// Networks returns the networks of every engine of the cluster.
+func (c *Cluster) Networks() cluster.Networks {
+	networks := cluster.Networks{}
+	for _, engine := range c.listEngines() {
+		networks = append(networks, engine.Networks()...)
+	}
+	return networks
+}
+
+// CreateNetwork creates a network on the engine its name is prefixed with
+// (ex. node-1/backend), or on any engine. Networks of multi-host drivers then
+// show up on every engine.
+func (c *Cluster) CreateNetwork(request *cluster.NetworkCreateRequest) (*cluster.NetworkCreateResponse, error) {
+	var engine *cluster.Engine
+	if i := strings.Index(request.Name, "/"); i >= 0 {
+		name := request.Name[:i]
+		if engine = c.getEngine(name); engine == nil {
+			return nil, fmt.Errorf("No such node: %s", name)
+		}
+		request.Name = request.Name[i+1:]
+	} else {
+		var err error
+		if engine, err = c.RANDOMENGINE(); err != nil {
+			return nil, err
+		}
+		if engine == nil {
+			return nil, errNoMatchingNode
+		}
+	}
+
+	response, err := engine.CreateNetwork(request)
+	if err != nil {
+		return nil, err
+	}
+	c.refreshNetworks(engine)
+	return response, nil
+}
+
+// RemoveNetwork removes a network from its engine, and from every engine for
+// the networks of multi-host drivers.
+func (c *Cluster) RemoveNetwork(network *cluster.Network) error {
+	if err := network.Engine.RemoveNetwork(network); err != nil {
+		return err
+	}
+	if !network.IsLocal() {
+		c.refreshNetworks(network.Engine)
+	}
+	return nil
+}
+
+// refreshNetworks refreshes the networks of the engines other than the one a
+// network was just created or removed on.
+func (c *Cluster) refreshNetworks(except *cluster.Engine) {
+	for _, engine := range c.listEngines() {
+		if engine != except && engine.IsHealthy() {
+			engine.RefreshNetworks()
+		}
+	}
+}
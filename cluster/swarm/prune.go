@@ -0,0 +1,111 @@
+package swarm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/docker/pkg/units"
+	"github.com/docker/swarm/cluster"
+)
+
+// parsePruneOptions returns the interval and the policy of the scheduled
+// image garbage collection. The interval is 0 when it is disabled.
+func parsePruneOptions(options cluster.DriverOpts) (time.Duration, *cluster.ImagePrunePolicy, error) {
+	var (
+		interval time.Duration
+		policy   = &cluster.ImagePrunePolicy{}
+		err      error
+	)
+
+	if val, ok := options.String("swarm.prune.interval", ""); ok {
+		if interval, err = time.ParseDuration(val); err != nil || interval < 0 {
+			return 0, nil, fmt.Errorf("invalid value %q for swarm.prune.interval", val)
+		}
+	}
+	if val, ok := options.String("swarm.prune.age", ""); ok {
+		if policy.MinAge, err = time.ParseDuration(val); err != nil || policy.MinAge < 0 {
+			return 0, nil, fmt.Errorf("invalid value %q for swarm.prune.age", val)
+		}
+	}
+	if val, ok := options.String("swarm.prune.keep", ""); ok {
+		for _, name := range strings.Split(val, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				policy.Keep = append(policy.Keep, name)
+			}
+		}
+	}
+	if val, ok := options.Int("swarm.prune.keep-tags", ""); ok {
+		if val < 0 {
+			return 0, nil, fmt.Errorf("invalid value %d for swarm.prune.keep-tags", val)
+		}
+		policy.KeepTags = int(val)
+	}
+	// Tagged images are only removed with an explicit policy.
+	policy.DanglingOnly = policy.MinAge == 0 && len(policy.Keep) == 0 && policy.KeepTags == 0
+	if val, ok := options.String("swarm.prune.dangling", ""); ok {
+		if policy.DanglingOnly, err = strconv.ParseBool(val); err != nil {
+			return 0, nil, fmt.Errorf("invalid value %q for swarm.prune.dangling", val)
+		}
+	}
+	return interval, policy, nil
+}
+
+// PruneImages removes the images selected by the policy from every healthy
+// engine, in parallel.
+func (c *Cluster) PruneImages(policy *cluster.ImagePrunePolicy) ([]*cluster.ImagePruneReport, error) {
+	if policy == nil {
+		policy = &cluster.ImagePrunePolicy{DanglingOnly: true}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		engines = c.listEngines()
+		reports = make([]*cluster.ImagePruneReport, len(engines))
+	)
+	for i, engine := range engines {
+		if !engine.IsHealthy() {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, engine *cluster.Engine) {
+			defer wg.Done()
+			reports[i] = engine.PruneImages(policy)
+		}(i, engine)
+	}
+	wg.Wait()
+
+	result := []*cluster.ImagePruneReport{}
+	for _, report := range reports {
+		if report != nil {
+			result = append(result, report)
+		}
+	}
+	return result, nil
+}
+
+// pruneLoop periodically removes the unused images while this manager is the
+// primary.
+func (c *Cluster) pruneLoop() {
+	for range time.Tick(c.pruneInterval) {
+		if !c.isPrimary() {
+			continue
+		}
+		reports, err := c.PruneImages(c.prunePolicy)
+		if err != nil {
+			log.Errorf("Unable to prune images: %v", err)
+			continue
+		}
+		for _, report := range reports {
+			if len(report.Removed) > 0 || len(report.Errors) > 0 {
+				log.WithFields(log.Fields{"name": report.Node}).Infof("Pruned %d images, %s reclaimed", len(report.Removed), units.HumanSize(float64(report.Reclaimed)))
+			}
+			for _, e := range report.Errors {
+				log.WithFields(log.Fields{"name": report.Node}).Warnf("Unable to prune image %s", e)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package swarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePruneOptions(t *testing.T) {
+	interval, policy, err := parsePruneOptions(cluster.DriverOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, interval, time.Duration(0))
+	assert.Equal(t, policy, &cluster.ImagePrunePolicy{DanglingOnly: true})
+
+	// Without a policy, only untagged images are removed.
+	interval, policy, err = parsePruneOptions(cluster.DriverOpts{"swarm.prune.interval=1h"})
+	assert.NoError(t, err)
+	assert.Equal(t, interval, time.Hour)
+	assert.Equal(t, policy, &cluster.ImagePrunePolicy{DanglingOnly: true})
+
+	interval, policy, err = parsePruneOptions(cluster.DriverOpts{"swarm.prune.interval=1h", "swarm.prune.age=24h", "swarm.prune.keep=swarm, redis:3.0", "swarm.prune.keep-tags=2"})
+	assert.NoError(t, err)
+	assert.Equal(t, interval, time.Hour)
+	assert.Equal(t, policy, &cluster.ImagePrunePolicy{MinAge: 24 * time.Hour, Keep: []string{"swarm", "redis:3.0"}, KeepTags: 2})
+
+	_, policy, err = parsePruneOptions(cluster.DriverOpts{"swarm.prune.interval=1h", "swarm.prune.dangling=false"})
+	assert.NoError(t, err)
+	assert.Equal(t, policy, &cluster.ImagePrunePolicy{})
+
+	for _, option := range []string{"swarm.prune.interval=soon", "swarm.prune.age=-1h", "swarm.prune.keep-tags=-1", "swarm.prune.dangling=maybe"} {
+		_, _, err = parsePruneOptions(cluster.DriverOpts{option})
+		assert.Error(t, err, option)
+	}
+}
+
+func TestPruneImages(t *testing.T) {
+	c := newMockCluster()
+	addMockEngine(t, c, "1.1.1.1:1234", "aa", []*dockerclient.Image{
+		{Id: "layer", RepoTags: []string{"<none>:<none>"}},
+		{Id: "unused", ParentId: "layer", RepoTags: []string{"unused:latest"}, Size: 10},
+		{Id: "dangling", ParentId: "layer", RepoTags: []string{"<none>:<none>"}, Size: 20},
+	})
+	addMockEngine(t, c, "2.2.2.2:1234", "bb", []*dockerclient.Image{})
+
+	reports, err := c.PruneImages(&cluster.ImagePrunePolicy{DanglingOnly: true, DryRun: true})
+	assert.NoError(t, err)
+	assert.Len(t, reports, 2)
+	for _, report := range reports {
+		switch report.Node {
+		case "aa":
+			assert.Equal(t, report.Removed, []string{"dangling"})
+			assert.Equal(t, report.Reclaimed, int64(20))
+		case "bb":
+			assert.Empty(t, report.Removed)
+		default:
+			t.Fatalf("unexpected node %s", report.Node)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package swarm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+)
+
+// restartTracker keeps track of the failures of a container with a
+// cluster-level restart policy, across restarts and relocations.
+type restartTracker struct {
+	status cluster.RestartStatus
+
+	// Failures on the node the container currently runs on.
+	nodeFailures int
+	// The container was stopped or killed on purpose.
+	stopped bool
+	// The container was killed by the OOM killer.
+	oomKilled bool
+	// The container is being recreated on another node.
+	relocating bool
+	// ID of the container replaced by a move, whose events are ignored.
+	replacedID string
+}
+
+// handleRestartPolicy applies the cluster-level restart policy of the
+// container an event refers to.
+func (c *Cluster) handleRestartPolicy(e *cluster.Event) {
+	container := e.Container
+	if container == nil || container.Config == nil {
+		return
+	}
+	swarmID := container.Config.SwarmID()
+	if swarmID == "" {
+		return
+	}
+	policy, err := container.Config.SwarmRestartPolicy()
+	if err != nil {
+		log.WithFields(log.Fields{"name": container.Engine.Name, "id": container.Id}).Errorf("Ignoring restart policy: %v", err)
+		return
+	}
+	if policy == nil {
+		return
+	}
+
+	// Only the primary enforces the policy, or every replica would restart
+	// the container. Checked last, as engine events can be emitted with
+	// the cluster locked.
+	if !c.isPrimary() {
+		return
+	}
+
+	// The container was moved to another node: it is removed on purpose.
+	c.restartsLock.Lock()
+	tracker, ok := c.restarts[swarmID]
+	replaced := ok && tracker.replacedID == container.Id
+	c.restartsLock.Unlock()
+	if replaced {
+		return
+	}
+
+	switch e.Status {
+	case "start":
+		c.restartsLock.Lock()
+		if tracker, ok := c.restarts[swarmID]; ok {
+			tracker.stopped = false
+		}
+		c.restartsLock.Unlock()
+	case "kill":
+		c.restartsLock.Lock()
+		c.getRestartTracker(swarmID).stopped = true
+		c.restartsLock.Unlock()
+	case "oom":
+		c.restartsLock.Lock()
+		c.getRestartTracker(swarmID).oomKilled = true
+		c.restartsLock.Unlock()
+	case "die":
+		go c.restartContainer(container, policy)
+	case "destroy":
+		c.restartsLock.Lock()
+		tracker, ok := c.restarts[swarmID]
+		relocating := ok && tracker.relocating
+		c.restartsLock.Unlock()
+
+		// Forget about the container unless it lives on somewhere else.
+		if ok && !relocating && c.getIDFromSwarmID(swarmID) == "" {
+			c.restartsLock.Lock()
+			delete(c.restarts, swarmID)
+			c.restartsLock.Unlock()
+		}
+	}
+}
+
+// getRestartTracker returns the restart tracker of a Swarm ID, created if
+// needed. It must be called with restartsLock held.
+func (c *Cluster) getRestartTracker(swarmID string) *restartTracker {
+	tracker, ok := c.restarts[swarmID]
+	if !ok {
+		tracker = &restartTracker{}
+		c.restarts[swarmID] = tracker
+	}
+	return tracker
+}
+
+// setReplacedID sets the container whose events the restart policy of a Swarm
+// ID ignores, because it is being replaced on purpose.
+func (c *Cluster) setReplacedID(swarmID, id string) {
+	if swarmID == "" {
+		return
+	}
+	c.restartsLock.Lock()
+	c.getRestartTracker(swarmID).replacedID = id
+	c.restartsLock.Unlock()
+}
+
+// RestartStatus returns what the cluster-level restart policy did to the
+// container, nil if it never restarted it.
+func (c *Cluster) RestartStatus(container *cluster.Container) *cluster.RestartStatus {
+	if container.Config == nil || container.Config.SwarmID() == "" {
+		return nil
+	}
+
+	c.restartsLock.Lock()
+	defer c.restartsLock.Unlock()
+
+	tracker, ok := c.restarts[container.Config.SwarmID()]
+	if !ok || tracker.status.Count == 0 {
+		return nil
+	}
+	status := tracker.status
+	return &status
+}
+
+// restartContainer restarts a container that died, in place or on another
+// node, according to its restart policy.
+func (c *Cluster) restartContainer(container *cluster.Container, policy *cluster.RestartPolicy) {
+	swarmID := container.Config.SwarmID()
+	exitCode := 0
+	if container.Info.State != nil {
+		exitCode = container.Info.State.ExitCode
+	}
+
+	c.restartsLock.Lock()
+	tracker := c.getRestartTracker(swarmID)
+	oomKilled := tracker.oomKilled
+	tracker.oomKilled = false
+	if tracker.stopped || !policy.ShouldRestart(exitCode, tracker.status.Count) {
+		c.restartsLock.Unlock()
+		return
+	}
+
+	tracker.nodeFailures++
+	tracker.status.Count++
+	tracker.status.LastFailureAt = time.Now()
+	if oomKilled {
+		tracker.status.LastFailure = fmt.Sprintf("OOM killed on %s", container.Engine.Name)
+	} else {
+		tracker.status.LastFailure = fmt.Sprintf("exited with code %d on %s", exitCode, container.Engine.Name)
+	}
+
+	relocate := oomKilled || (policy.RelocateAfter > 0 && tracker.nodeFailures >= policy.RelocateAfter)
+	if relocate {
+		tracker.nodeFailures = 0
+		tracker.relocating = true
+		tracker.status.Relocations++
+	}
+	status := tracker.status
+	c.restartsLock.Unlock()
+
+	log.WithFields(log.Fields{"name": container.Engine.Name, "id": container.Id}).Infof("Restarting container (%s, restart #%d)", status.LastFailure, status.Count)
+
+	if !relocate {
+		if err := container.Engine.StartContainer(container); err != nil {
+			log.WithFields(log.Fields{"name": container.Engine.Name, "id": container.Id}).Errorf("Unable to restart container: %v", err)
+		}
+		return
+	}
+
+	relocated, err := c.relocateContainer(container)
+
+	c.restartsLock.Lock()
+	tracker.relocating = false
+	c.restartsLock.Unlock()
+
+	if err != nil {
+		log.WithFields(log.Fields{"name": container.Engine.Name, "id": container.Id}).Errorf("Unable to relocate container: %v", err)
+		return
+	}
+	log.WithFields(log.Fields{"name": relocated.Engine.Name, "id": relocated.Id}).Infof("Container relocated from %s", container.Engine.Name)
+}
+
+// relocateContainer recreates and starts a container on another node. The new
+// container keeps the name and the Swarm ID of the old one, which is only
+// removed once the new one is started.
+func (c *Cluster) relocateContainer(container *cluster.Container) (*cluster.Container, error) {
+	return c.replaceContainer(container, &placement{excludedID: container.Engine.ID, replaced: container}, true)
+}
+
+// recreateConfig returns the config a container is recreated with, including
+// its Swarm ID.
+func recreateConfig(container *cluster.Container) *cluster.ContainerConfig {
+	return cluster.BuildContainerConfig(recreateDockerConfig(container))
+}
+
+// recreateDockerConfig returns a copy of the docker config of a container, to
+// be changed before the container is recreated.
+func recreateDockerConfig(container *cluster.Container) dockerclient.ContainerConfig {
+	// Copy the labels, the config is rebuilt from scratch.
+	labels := make(map[string]string, len(container.Config.Labels))
+	for k, v := range container.Config.Labels {
+		labels[k] = v
+	}
+	dockerConfig := container.Config.ContainerConfig
+	dockerConfig.Labels = labels
+	if container.Info.HostConfig != nil {
+		dockerConfig.HostConfig = *container.Info.HostConfig
+	}
+	return dockerConfig
+}
+
+// containerName returns the name of the container, without its leading /.
+func containerName(container *cluster.Container) string {
+	if len(container.Names) > 0 {
+		return strings.TrimPrefix(container.Names[0], "/")
+	}
+	return ""
+}
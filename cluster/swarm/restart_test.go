@@ -0,0 +1,138 @@
+package swarm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRestartContainerInPlace(t *testing.T) {
+	c := newMockCluster()
+
+	labels := map[string]string{
+		"com.docker.swarm.id":      "swarm-id",
+		"com.docker.swarm.restart": "on-failure:2",
+	}
+	engine, client := addMockEngine(t, c, "engine", "engine", nil, dockerclient.Container{Id: "container-id", Names: []string{"/name"}, Labels: labels})
+	container := engine.Containers()[0]
+
+	client.On("StartContainer", "container-id", mock.Anything).Return(nil).Twice()
+	client.On("ListContainers", true, false, mock.Anything).Return([]dockerclient.Container{container.Container}, nil)
+
+	policy, err := container.Config.SwarmRestartPolicy()
+	assert.NoError(t, err)
+
+	// Restarted until the maximum restart count is reached.
+	c.restartContainer(container, policy)
+	c.restartContainer(container, policy)
+	c.restartContainer(container, policy)
+	client.AssertNumberOfCalls(t, "StartContainer", 2)
+
+	status := c.RestartStatus(container)
+	assert.NotNil(t, status)
+	assert.Equal(t, status.Count, 2)
+	assert.Equal(t, status.Relocations, 0)
+	assert.Equal(t, status.LastFailure, "exited with code 1 on engine")
+
+	// Containers killed on purpose are not restarted.
+	delete(c.restarts, "swarm-id")
+	c.handleRestartPolicy(&cluster.Event{Event: dockerclient.Event{Status: "kill", Id: "container-id"}, Engine: engine, Container: container})
+	c.restartContainer(container, policy)
+	client.AssertNumberOfCalls(t, "StartContainer", 2)
+
+	// The container is forgotten once destroyed.
+	client.On("RemoveContainer", "container-id", true, true).Return(nil).Once()
+	assert.NoError(t, engine.RemoveContainer(container, true))
+	c.handleRestartPolicy(&cluster.Event{Event: dockerclient.Event{Status: "destroy", Id: "container-id"}, Engine: engine, Container: container})
+	assert.Empty(t, c.restarts)
+}
+
+func TestRelocateContainer(t *testing.T) {
+	c := newMockCluster()
+
+	labels := map[string]string{
+		"com.docker.swarm.id":      "swarm-id",
+		"com.docker.swarm.restart": "always,relocate-after=2",
+	}
+	engine1, client1 := addMockEngine(t, c, "engine1", "engine1", nil, dockerclient.Container{Id: "container-id", Names: []string{"/name"}, Labels: labels})
+	engine2, client2 := addMockEngine(t, c, "engine2", "engine2", nil)
+	container := engine1.Containers()[0]
+	policy, err := container.Config.SwarmRestartPolicy()
+	assert.NoError(t, err)
+
+	relocated := dockerclient.Container{Id: "relocated-id", Names: []string{"/name"}, Labels: labels}
+	client1.On("StartContainer", "container-id", mock.Anything).Return(nil).Once()
+	client1.On("ListContainers", true, false, mock.Anything).Return([]dockerclient.Container{container.Container}, nil)
+	client1.On("RemoveContainer", "container-id", true, true).Return(nil).Once()
+	client2.On("CreateContainer", mock.Anything, "name").Return("relocated-id", nil).Once()
+	client2.On("ListContainers", true, false, mock.Anything).Return([]dockerclient.Container{relocated}, nil)
+	client2.On("InspectContainer", "relocated-id").Return(&dockerclient.ContainerInfo{
+		Id:     "relocated-id",
+		Config: &dockerclient.ContainerConfig{Image: "busybox", Labels: labels},
+		State:  &dockerclient.State{Running: true},
+	}, nil)
+	client2.On("StartContainer", "relocated-id", mock.Anything).Return(nil).Once()
+
+	// The first failure restarts the container in place, the second one
+	// relocates it to the other engine.
+	c.restartContainer(container, policy)
+	c.restartContainer(container, policy)
+	client1.AssertExpectations(t)
+	client2.AssertExpectations(t)
+
+	assert.Len(t, engine1.Containers(), 0)
+	assert.Len(t, engine2.Containers(), 1)
+	moved := engine2.Containers()[0]
+	assert.Equal(t, moved.Config.SwarmID(), "swarm-id")
+	status := c.RestartStatus(moved)
+	assert.NotNil(t, status)
+	assert.Equal(t, status.Count, 2)
+	assert.Equal(t, status.Relocations, 1)
+
+	// The container lives on, the destroy event of the old one is ignored.
+	c.handleRestartPolicy(&cluster.Event{Event: dockerclient.Event{Status: "destroy", Id: "container-id"}, Engine: engine1, Container: container})
+	assert.Len(t, c.restarts, 1)
+}
+
+func TestRelocateContainerFailure(t *testing.T) {
+	c := newMockCluster()
+
+	labels := map[string]string{
+		"com.docker.swarm.id":      "swarm-id",
+		"com.docker.swarm.restart": "always,relocate-after=1",
+	}
+	engine1, client1 := addMockEngine(t, c, "engine1", "engine1", nil, dockerclient.Container{Id: "container-id", Names: []string{"/name"}, Labels: labels})
+	_, client2 := addMockEngine(t, c, "engine2", "engine2", nil)
+	container := engine1.Containers()[0]
+
+	client2.On("CreateContainer", mock.Anything, "name").Return("", errors.New("no space left on device")).Once()
+
+	// The container is kept where it is when it can't be recreated.
+	_, err := c.relocateContainer(container)
+	assert.Error(t, err)
+	client1.AssertNotCalled(t, "RemoveContainer", "container-id", true, true)
+	client2.AssertExpectations(t)
+	assert.Equal(t, engine1.Containers(), cluster.Containers{container})
+}
+
+func TestRestartPolicyReplica(t *testing.T) {
+	c := newMockCluster()
+	c.primary = false
+
+	labels := map[string]string{
+		"com.docker.swarm.id":      "swarm-id",
+		"com.docker.swarm.restart": "always",
+	}
+	engine, client := addMockEngine(t, c, "engine", "engine", nil, dockerclient.Container{Id: "container-id", Names: []string{"/name"}, Labels: labels})
+	container := engine.Containers()[0]
+
+	// Only the primary restarts the container.
+	c.handleRestartPolicy(&cluster.Event{Event: dockerclient.Event{Status: "kill", Id: "container-id"}, Engine: engine, Container: container})
+	c.handleRestartPolicy(&cluster.Event{Event: dockerclient.Event{Status: "die", Id: "container-id"}, Engine: engine, Container: container})
+	assert.Empty(t, c.restarts)
+	client.AssertNotCalled(t, "StartContainer", "container-id", mock.Anything)
+}
@@ -0,0 +1,170 @@
+package swarm
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/libkv/store"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/discovery"
+)
+
+const (
+	// Path of the cluster state snapshot in the KV store, relative to the
+	// discovery prefix.
+	snapshotPath = "docker/swarm/snapshot"
+)
+
+// Retry connecting to an engine restored from a snapshot this often.
+var restoredEngineRetryPeriod = 30 * time.Second
+
+// snapshot is the state of the cluster as persisted in the KV store.
+type snapshot struct {
+	Time    int64
+	Engines []*cluster.EngineSnapshot
+}
+
+// SetPrimary tells the cluster whether this manager is the primary. Only the
+// primary writes snapshots of the cluster state.
+func (c *Cluster) SetPrimary(primary bool) {
+	c.Lock()
+	c.primary = primary
+	c.Unlock()
+}
+
+func (c *Cluster) isPrimary() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.primary
+}
+
+// saveSnapshot writes a snapshot of every engine to the KV store.
+func (c *Cluster) saveSnapshot() error {
+	s := snapshot{Time: time.Now().Unix()}
+	for _, engine := range c.listEngines() {
+		s.Engines = append(s.Engines, engine.Snapshot())
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return c.snapshotStore.Put(c.snapshotKey, data, nil)
+}
+
+// loadSnapshot reads the last snapshot from the KV store. It returns nil if
+// no snapshot was ever written.
+func (c *Cluster) loadSnapshot() (*snapshot, error) {
+	pair, err := c.snapshotStore.Get(c.snapshotKey)
+	if err == store.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &snapshot{}
+	if err := json.Unmarshal(pair.Value, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// restoreSnapshot registers the engines of the last snapshot so that reads
+// can be served right away, and starts connecting to them. It returns the
+// discovery entries of the restored engines.
+func (c *Cluster) restoreSnapshot() discovery.Entries {
+	entries := discovery.Entries{}
+	if c.snapshotStore == nil {
+		return entries
+	}
+
+	s, err := c.loadSnapshot()
+	if err != nil {
+		log.Errorf("Unable to load cluster snapshot: %v", err)
+		return entries
+	}
+	if s == nil {
+		return entries
+	}
+
+	c.Lock()
+	for _, es := range s.Engines {
+		entry, err := discovery.NewEntry(es.Addr)
+		if err != nil {
+			log.Errorf("Ignoring engine %s from snapshot: %v", es.Addr, err)
+			continue
+		}
+		if _, exists := c.engines[es.ID]; exists {
+			continue
+		}
+
+		engine := cluster.NewEngineFromSnapshot(es, c.overcommitRatio)
+		engine.SetSystemReserved(c.reservedMemory, c.reservedMilliCpus)
+		engine.SetResourcePolicy(c.resourcePolicy)
+		if err := engine.RegisterEventHandler(c); err != nil {
+			log.Error(err)
+		}
+		c.engines[engine.ID] = engine
+		entries = append(entries, entry)
+
+		go c.connectRestoredEngine(engine)
+	}
+	c.Unlock()
+
+	log.Infof("Restored %d engines from the snapshot taken at %s", len(entries), time.Unix(s.Time, 0))
+	return entries
+}
+
+// connectRestoredEngine connects to an engine restored from a snapshot and
+// reconciles its state with the real one. An unreachable engine is kept,
+// flagged as unhealthy, until a connection succeeds: the discovery won't add
+// it again as long as its address is listed.
+func (c *Cluster) connectRestoredEngine(engine *cluster.Engine) {
+	restored := make(map[string]bool)
+	for _, container := range engine.Containers() {
+		restored[container.Id] = true
+	}
+
+	for {
+		err := engine.Connect(c.TLSConfig)
+		if err == nil {
+			break
+		}
+		log.WithFields(log.Fields{"name": engine.Name, "id": engine.ID}).Errorf("Unable to connect to restored engine, retrying in %s: %v", restoredEngineRetryPeriod, err)
+		engine.SetUnreachable()
+
+		time.Sleep(restoredEngineRetryPeriod)
+		if c.getEngineByAddr(engine.Addr) != engine {
+			// The engine left the discovery in the meantime.
+			return
+		}
+	}
+
+	// The engine may have been reinstalled since the snapshot.
+	c.updateEngineID(engine)
+
+	added := 0
+	for _, container := range engine.Containers() {
+		if restored[container.Id] {
+			delete(restored, container.Id)
+		} else {
+			added++
+		}
+	}
+	log.WithFields(log.Fields{"name": engine.Name, "id": engine.ID}).Infof("Reconciled engine with snapshot: %d containers added, %d removed", added, len(restored))
+}
+
+// snapshotLoop periodically saves the cluster state while this manager is
+// the primary.
+func (c *Cluster) snapshotLoop() {
+	for range time.Tick(c.snapshotInterval) {
+		if !c.isPrimary() {
+			continue
+		}
+		if err := c.saveSnapshot(); err != nil {
+			log.Errorf("Unable to save cluster snapshot: %v", err)
+		}
+	}
+}
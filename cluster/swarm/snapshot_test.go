@@ -0,0 +1,123 @@
+package swarm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/docker/libkv/store"
+	libkvmock "github.com/docker/libkv/store/mock"
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSaveSnapshot(t *testing.T) {
+	kv, err := libkvmock.New([]string{"127.0.0.1"}, nil)
+	assert.NoError(t, err)
+	storeMock := kv.(*libkvmock.Mock)
+
+	c := &Cluster{
+		engines:       make(map[string]*cluster.Engine),
+		snapshotStore: kv,
+		snapshotKey:   "prefix/" + snapshotPath,
+	}
+	engine := createEngine(t, "test-engine", &cluster.Container{
+		Container: dockerclient.Container{Id: "container-id"},
+		Config:    cluster.BuildContainerConfig(dockerclient.ContainerConfig{}),
+	})
+	c.engines[engine.ID] = engine
+
+	var saved []byte
+	storeMock.On("Put", "prefix/"+snapshotPath, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		saved = args.Get(1).([]byte)
+	}).Once()
+	assert.NoError(t, c.saveSnapshot())
+
+	s := &snapshot{}
+	assert.NoError(t, json.Unmarshal(saved, s))
+	if assert.Len(t, s.Engines, 1) {
+		assert.Equal(t, s.Engines[0].ID, "test-engine")
+		if assert.Len(t, s.Engines[0].Containers, 1) {
+			assert.Equal(t, s.Engines[0].Containers[0].Id, "container-id")
+		}
+	}
+
+	storeMock.AssertExpectations(t)
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	kv, err := libkvmock.New([]string{"127.0.0.1"}, nil)
+	assert.NoError(t, err)
+	storeMock := kv.(*libkvmock.Mock)
+
+	c := &Cluster{
+		engines:       make(map[string]*cluster.Engine),
+		snapshotStore: kv,
+		snapshotKey:   snapshotPath,
+	}
+
+	// No snapshot yet.
+	storeMock.On("Get", snapshotPath).Return((*store.KVPair)(nil), store.ErrKeyNotFound).Once()
+	s, err := c.loadSnapshot()
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	data, err := json.Marshal(snapshot{
+		Time: 42,
+		Engines: []*cluster.EngineSnapshot{
+			{ID: "id1", Addr: "127.0.0.1:2375", Name: "node1"},
+		},
+	})
+	assert.NoError(t, err)
+	storeMock.On("Get", snapshotPath).Return(&store.KVPair{Key: snapshotPath, Value: data}, nil).Once()
+	s, err = c.loadSnapshot()
+	assert.NoError(t, err)
+	if assert.NotNil(t, s) {
+		assert.Equal(t, s.Time, int64(42))
+		assert.Len(t, s.Engines, 1)
+		assert.Equal(t, s.Engines[0].Name, "node1")
+	}
+
+	storeMock.AssertExpectations(t)
+}
+
+func TestSetPrimary(t *testing.T) {
+	c := &Cluster{engines: make(map[string]*cluster.Engine)}
+	assert.False(t, c.isPrimary())
+	c.SetPrimary(true)
+	assert.True(t, c.isPrimary())
+	c.SetPrimary(false)
+	assert.False(t, c.isPrimary())
+}
+
+func TestConnectRestoredEngine(t *testing.T) {
+	defer func(period time.Duration) { restoredEngineRetryPeriod = period }(restoredEngineRetryPeriod)
+	restoredEngineRetryPeriod = 10 * time.Millisecond
+
+	c := &Cluster{engines: make(map[string]*cluster.Engine)}
+	// Nothing listens on this port.
+	engine := cluster.NewEngineFromSnapshot(&cluster.EngineSnapshot{ID: "id1", Addr: "127.0.0.1:1", Name: "node1"}, 0)
+	c.engines[engine.ID] = engine
+	assert.True(t, engine.IsHealthy())
+
+	done := make(chan struct{})
+	go func() {
+		c.connectRestoredEngine(engine)
+		close(done)
+	}()
+
+	// The engine is kept, unhealthy, while the connection is retried.
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, engine.IsHealthy())
+	assert.Equal(t, c.getEngineByAddr("127.0.0.1:1"), engine)
+
+	// The retries stop once the engine leaves the discovery.
+	c.removeEngine("127.0.0.1:1")
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("connectRestoredEngine didn't return after the engine was removed")
+	}
+}
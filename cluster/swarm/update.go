@@ -0,0 +1,245 @@
+package swarm
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+)
+
+// Seconds a container is given to stop before being killed by an update.
+const updateStopTimeout = 10
+
+// containerUpdate is the replacement of a container by an update. The old
+// container is kept, stopped and renamed, until the update is over so that it
+// can be restored.
+type containerUpdate struct {
+	name    string
+	old     *cluster.Container
+	new     *cluster.Container
+	running bool
+	renamed bool
+}
+
+// UpdateContainers recreates the containers with the options, in batches of
+// `options.BatchSize` containers. A container which can't be recreated, or
+// whose new container doesn't keep running, is restored and the update stops:
+// the containers already updated are kept, or restored too with
+// `options.Rollback`.
+func (c *Cluster) UpdateContainers(containers []*cluster.Container, options *cluster.UpdateOptions, callback func(where, status string, err error)) error {
+	batchSize := options.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	for _, container := range containers {
+		callback(containerName(container), "Waiting", nil)
+	}
+
+	var (
+		updated []*containerUpdate
+		failure error
+	)
+	for i := 0; i < len(containers) && failure == nil; i += batchSize {
+		batch := containers[i:]
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+
+		updates := make([]*containerUpdate, len(batch))
+		errs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for j, container := range batch {
+			updates[j] = &containerUpdate{name: containerName(container), old: container}
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				errs[j] = c.updateContainer(updates[j], options, callback)
+			}(j)
+		}
+		wg.Wait()
+
+		for j, u := range updates {
+			if errs[j] != nil {
+				callback(u.name, "", errs[j])
+				if err := c.restoreContainer(u); err != nil {
+					callback(u.name, "", err)
+				} else {
+					callback(u.name, "Restored", nil)
+				}
+				if failure == nil {
+					failure = fmt.Errorf("Unable to update container %s: %v", u.name, errs[j])
+				}
+				continue
+			}
+
+			// The old containers are only needed to roll back the update.
+			if !options.Rollback {
+				c.finishUpdate(u, callback)
+			}
+			updated = append(updated, u)
+		}
+	}
+
+	if failure != nil && options.Rollback {
+		for _, u := range updated {
+			if err := c.restoreContainer(u); err != nil {
+				callback(u.name, "", err)
+			} else {
+				callback(u.name, "Rolled back", nil)
+			}
+		}
+		return fmt.Errorf("Update rolled back: %v", failure)
+	}
+
+	if options.Rollback {
+		for _, u := range updated {
+			c.finishUpdate(u, callback)
+		}
+	}
+	if failure != nil {
+		return fmt.Errorf("Update paused after %d of %d containers: %v", len(updated), len(containers), failure)
+	}
+	return nil
+}
+
+// updateContainer stops and renames the old container, then creates the new
+// one and starts it if the old one was running.
+func (c *Cluster) updateContainer(u *containerUpdate, options *cluster.UpdateOptions, callback func(where, status string, err error)) error {
+	dockerConfig := recreateDockerConfig(u.old)
+	options.Apply(&dockerConfig)
+	config := cluster.BuildContainerConfig(dockerConfig)
+
+	// The restart policy doesn't apply to the old container anymore.
+	c.setReplacedID(config.SwarmID(), u.old.Id)
+
+	u.running = u.old.Info.State != nil && u.old.Info.State.Running
+	if u.running {
+		callback(u.name, fmt.Sprintf("Stopping on %s", u.old.Engine.Name), nil)
+		if err := u.old.Engine.StopContainer(u.old, updateStopTimeout); err != nil {
+			return err
+		}
+	}
+
+	// The old container frees its name for the new one.
+	id := u.old.Id
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	if err := u.old.Engine.RenameContainer(u.old, u.name+"-"+id); err != nil {
+		return err
+	}
+	u.renamed = true
+
+	var err error
+	if options.Reschedule {
+		u.new, err = c.createContainerWith(config, u.name, &placement{replaced: u.old})
+	} else {
+		u.new, err = c.recreateContainerOn(config, u.name, u.old.Engine)
+	}
+	if err != nil {
+		return err
+	}
+	if u.new == nil {
+		return fmt.Errorf("unable to recreate container %s", u.name)
+	}
+	if !u.running {
+		callback(u.name, fmt.Sprintf("Created on %s", u.new.Engine.Name), nil)
+		return nil
+	}
+
+	callback(u.name, fmt.Sprintf("Starting on %s", u.new.Engine.Name), nil)
+	if err := u.new.Engine.StartContainer(u.new); err != nil {
+		return err
+	}
+	// The new container is checked once started even without a monitoring
+	// window, so that an image which crashes right away isn't rolled out.
+	callback(u.name, fmt.Sprintf("Monitoring on %s", u.new.Engine.Name), nil)
+	if err := c.monitorContainer(u.new, options.Monitor); err != nil {
+		return err
+	}
+	callback(u.name, fmt.Sprintf("Running on %s", u.new.Engine.Name), nil)
+	return nil
+}
+
+// recreateContainerOn creates a container replacing another one on the engine
+// of the old container. It doesn't go through the scheduler, whose filters
+// would count the resources and the ports of the old container.
+func (c *Cluster) recreateContainerOn(config *cluster.ContainerConfig, name string, engine *cluster.Engine) (*cluster.Container, error) {
+	c.scheduler.Lock()
+	defer c.scheduler.Unlock()
+
+	if c.pinImages {
+		if err := c.pinImage(config, func() (*cluster.Engine, error) { return engine, nil }); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.resourcePolicy.Check(config); err != nil {
+		return nil, err
+	}
+	c.resourcePolicy.Apply(config)
+	if err := config.ValidateReservations(); err != nil {
+		return nil, err
+	}
+	return engine.Create(config, name, true)
+}
+
+// monitorContainer returns an error if the container isn't running anymore
+// after `delay`, or was restarted in the meantime.
+func (c *Cluster) monitorContainer(container *cluster.Container, delay time.Duration) error {
+	var startedAt time.Time
+	if container.Info.State != nil {
+		startedAt = container.Info.State.StartedAt
+	}
+	time.Sleep(delay)
+
+	refreshed, err := container.Engine.RefreshContainer(container.Id)
+	if err != nil {
+		return err
+	}
+	if refreshed == nil {
+		return fmt.Errorf("container %s was removed", container.Id)
+	}
+	state := refreshed.Info.State
+	if state == nil || !state.Running {
+		return fmt.Errorf("container %s isn't running anymore", container.Id)
+	}
+	if !state.StartedAt.Equal(startedAt) {
+		return fmt.Errorf("container %s was restarted", container.Id)
+	}
+	return nil
+}
+
+// restoreContainer removes the new container of an update and restores the
+// old one.
+func (c *Cluster) restoreContainer(u *containerUpdate) error {
+	if u.new != nil {
+		c.setReplacedID(u.new.Config.SwarmID(), u.new.Id)
+		if err := c.RemoveContainer(u.new, true); err != nil {
+			return fmt.Errorf("Unable to remove the new container of %s: %v", u.name, err)
+		}
+		u.new = nil
+	}
+
+	if u.renamed {
+		if err := u.old.Engine.RenameContainer(u.old, u.name); err != nil {
+			return fmt.Errorf("Unable to rename container %s back: %v", u.name, err)
+		}
+		u.renamed = false
+	}
+	if u.running {
+		if err := u.old.Engine.StartContainer(u.old); err != nil {
+			return fmt.Errorf("Unable to restart container %s: %v", u.name, err)
+		}
+	}
+	return nil
+}
+
+// finishUpdate removes the old container of an update.
+func (c *Cluster) finishUpdate(u *containerUpdate, callback func(where, status string, err error)) {
+	if err := c.RemoveContainer(u.old, true); err != nil {
+		log.WithFields(log.Fields{"name": u.old.Engine.Name, "id": u.old.Id}).Errorf("Unable to remove container after an update: %v", err)
+	}
+	callback(u.name, "Updated", nil)
+}
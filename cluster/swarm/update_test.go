@@ -0,0 +1,135 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockUpdate sets up the engine for the update of container `name`, whose
+// new container is `newID`.
+func mockUpdate(client *mockclient.MockClient, name, newID string, images map[string]string) {
+	mockUpdateState(client, name, newID, images, true)
+}
+
+// mockUpdateState is mockUpdate with the state the new container is
+// inspected with once started.
+func mockUpdateState(client *mockclient.MockClient, name, newID string, images map[string]string, running bool) {
+	old := dockerclient.Container{Id: name + "-id", Names: []string{"/" + name}}
+	moved := dockerclient.Container{Id: newID, Names: []string{"/" + name}}
+	client.On("ListContainers", true, false, fmt.Sprintf("{%q:[%q]}", "id", old.Id)).Return([]dockerclient.Container{old}, nil)
+	client.On("ListContainers", true, false, fmt.Sprintf("{%q:[%q]}", "id", newID)).Return([]dockerclient.Container{moved}, nil)
+	client.On("InspectContainer", newID).Return(&dockerclient.ContainerInfo{
+		Id:     newID,
+		Config: &dockerclient.ContainerConfig{Image: "redis:3.0"},
+		State:  &dockerclient.State{Running: running},
+	}, nil)
+
+	client.On("StopContainer", old.Id, updateStopTimeout).Return(nil).Once()
+	client.On("RenameContainer", old.Id, name+"-"+old.Id).Return(nil).Once()
+	client.On("CreateContainer", mock.Anything, name).Return(newID, nil).Run(func(args mock.Arguments) {
+		images[name] = args.Get(0).(*dockerclient.ContainerConfig).Image
+	}).Once()
+}
+
+func updateCluster(t *testing.T) (*Cluster, []*cluster.Container, *mockclient.MockClient) {
+	c := newMockCluster()
+	_, client := addMockEngine(t, c, "engine1", "engine1", nil,
+		dockerclient.Container{Id: "web1-id", Names: []string{"/web1"}, Labels: map[string]string{"app": "web"}},
+		dockerclient.Container{Id: "web2-id", Names: []string{"/web2"}, Labels: map[string]string{"app": "web"}},
+	)
+
+	containers := []*cluster.Container{c.Container("web1"), c.Container("web2")}
+	for _, container := range containers {
+		container.Info.State.Running = true
+	}
+	return c, containers, client
+}
+
+func TestUpdateContainers(t *testing.T) {
+	c, containers, client := updateCluster(t)
+	images := make(map[string]string)
+	mockUpdate(client, "web1", "new1", images)
+	mockUpdate(client, "web2", "new2", images)
+	client.On("StartContainer", "new1", mock.Anything).Return(nil).Once()
+	client.On("StartContainer", "new2", mock.Anything).Return(nil).Once()
+	client.On("RemoveContainer", "web1-id", true, true).Return(nil).Once()
+	client.On("RemoveContainer", "web2-id", true, true).Return(nil).Once()
+
+	var mu sync.Mutex
+	statuses := make(map[string]string)
+	callback := func(where, status string, err error) {
+		assert.NoError(t, err)
+		mu.Lock()
+		statuses[where] = status
+		mu.Unlock()
+	}
+	err := c.UpdateContainers(containers, &cluster.UpdateOptions{Image: "redis:3.0", BatchSize: 2}, callback)
+	assert.NoError(t, err)
+	client.AssertExpectations(t)
+
+	assert.Equal(t, images, map[string]string{"web1": "redis:3.0", "web2": "redis:3.0"})
+	assert.Equal(t, statuses, map[string]string{"web1": "Updated", "web2": "Updated"})
+	assert.Equal(t, c.Container("web1").Id, "new1")
+	assert.Equal(t, c.Container("web2").Id, "new2")
+}
+
+func TestUpdateContainersRollback(t *testing.T) {
+	c, containers, client := updateCluster(t)
+	images := make(map[string]string)
+	mockUpdate(client, "web1", "new1", images)
+	mockUpdate(client, "web2", "new2", images)
+	client.On("StartContainer", "new1", mock.Anything).Return(nil).Once()
+	client.On("StartContainer", "new2", mock.Anything).Return(errors.New("port already allocated")).Once()
+
+	// Both containers are restored, the second one because it failed and
+	// the first one because the update is rolled back.
+	client.On("RemoveContainer", "new1", true, true).Return(nil).Once()
+	client.On("RemoveContainer", "new2", true, true).Return(nil).Once()
+	client.On("RenameContainer", "web1-id", "web1").Return(nil).Once()
+	client.On("RenameContainer", "web2-id", "web2").Return(nil).Once()
+	client.On("StartContainer", "web1-id", mock.Anything).Return(nil).Once()
+	client.On("StartContainer", "web2-id", mock.Anything).Return(nil).Once()
+
+	statuses := make(map[string]string)
+	callback := func(where, status string, err error) {
+		if err == nil {
+			statuses[where] = status
+		}
+	}
+	err := c.UpdateContainers(containers, &cluster.UpdateOptions{Image: "redis:3.0", Rollback: true}, callback)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "rolled back")
+	client.AssertExpectations(t)
+
+	assert.Equal(t, statuses, map[string]string{"web1": "Rolled back", "web2": "Restored"})
+	assert.Equal(t, c.Container("web1").Id, "web1-id")
+	assert.Equal(t, c.Container("web2").Id, "web2-id")
+}
+
+func TestUpdateContainersCrash(t *testing.T) {
+	c, containers, client := updateCluster(t)
+	images := make(map[string]string)
+	mockUpdateState(client, "web1", "new1", images, false)
+	client.On("StartContainer", "new1", mock.Anything).Return(nil).Once()
+
+	// The new container exits right after starting: it is replaced by the
+	// old one and the update stops, even without a monitoring window.
+	client.On("RemoveContainer", "new1", true, true).Return(nil).Once()
+	client.On("RenameContainer", "web1-id", "web1").Return(nil).Once()
+	client.On("StartContainer", "web1-id", mock.Anything).Return(nil).Once()
+
+	err := c.UpdateContainers(containers, &cluster.UpdateOptions{Image: "redis:3.0", BatchSize: 1}, func(string, string, error) {})
+	assert.Error(t, err)
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "StopContainer", "web2-id", updateStopTimeout)
+	assert.Equal(t, c.Container("web1").Id, "web1-id")
+	assert.Equal(t, c.Container("web2").Id, "web2-id")
+}
@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"strings"
+	"time"
+
+	"github.com/samalba/dockerclient"
+)
+
+// UpdateOptions describes a rolling update of containers: how they are
+// recreated, and how many of them at a time.
+type UpdateOptions struct {
+	// Image of the new containers, the image of the old ones if empty.
+	Image string
+	// Environment variables (KEY=value) added to the containers, replacing
+	// the variables with the same name.
+	Env []string
+	// Labels added to the containers, replacing the labels with the same key.
+	Labels map[string]string
+	// Number of containers recreated at the same time.
+	BatchSize int
+	// Time a new container must keep running to be healthy. With 0, the new
+	// container only has to be running once started.
+	Monitor time.Duration
+	// The containers are scheduled on any node instead of being recreated on
+	// their node.
+	Reschedule bool
+	// On failure, the containers already updated are rolled back rather than
+	// the update paused.
+	Rollback bool
+}
+
+// Apply updates the config of a container with the options.
+func (o *UpdateOptions) Apply(config *dockerclient.ContainerConfig) {
+	if o.Image != "" {
+		config.Image = o.Image
+		// The new image is pinned again when the container is created.
+		delete(config.Labels, SwarmLabelNamespace+".image")
+	}
+
+	for _, variable := range o.Env {
+		name := strings.SplitN(variable, "=", 2)[0]
+		env := []string{}
+		for _, existing := range config.Env {
+			if strings.SplitN(existing, "=", 2)[0] != name {
+				env = append(env, existing)
+			}
+		}
+		config.Env = append(env, variable)
+	}
+
+	if len(o.Labels) > 0 && config.Labels == nil {
+		config.Labels = make(map[string]string, len(o.Labels))
+	}
+	for k, v := range o.Labels {
+		config.Labels[k] = v
+	}
+}
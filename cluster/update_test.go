@@ -0,0 +1,35 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateOptionsApply(t *testing.T) {
+	config := dockerclient.ContainerConfig{
+		Image: "0123456789ab",
+		Env:   []string{"DEBUG=0", "PORT=80"},
+		Labels: map[string]string{
+			"com.docker.swarm.image": "redis:2.8",
+			"version":                "2",
+		},
+	}
+	options := &UpdateOptions{
+		Image:  "redis:3.0",
+		Env:    []string{"DEBUG=1", "LOG=info"},
+		Labels: map[string]string{"version": "3"},
+	}
+	options.Apply(&config)
+
+	assert.Equal(t, config.Image, "redis:3.0")
+	assert.Equal(t, config.Env, []string{"PORT=80", "DEBUG=1", "LOG=info"})
+	assert.Equal(t, config.Labels, map[string]string{"version": "3"})
+
+	// The image is kept without a new one.
+	config = dockerclient.ContainerConfig{Image: "redis:2.8"}
+	(&UpdateOptions{Labels: map[string]string{"version": "3"}}).Apply(&config)
+	assert.Equal(t, config.Image, "redis:2.8")
+	assert.Equal(t, config.Labels, map[string]string{"version": "3"})
+}
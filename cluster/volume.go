@@ -1,6 +1,11 @@
 package cluster
 
-import "github.com/samalba/dockerclient"
+import (
+	"sort"
+	"strings"
+
+	"github.com/samalba/dockerclient"
+)
 
 // Volume is exported
 type Volume struct {
@@ -8,3 +13,91 @@ type Volume struct {
 
 	Engine *Engine
 }
+
+// Volumes represents a list of volumes
+type Volumes []*Volume
+
+// Get returns a volume using its name, prefixed or not with the name or the
+// ID of its engine (ex. node-1/data). A name without prefix matches nothing
+// when several engines have a volume with this name.
+func (volumes Volumes) Get(name string) *Volume {
+	// Abort immediately if the name is empty.
+	if len(name) == 0 {
+		return nil
+	}
+
+	// Match engine/name.
+	for _, volume := range volumes {
+		if volume.Engine.Name+"/"+volume.Name == name || volume.Engine.ID+"/"+volume.Name == name {
+			return volume
+		}
+	}
+
+	// Match name.
+	candidates := []*Volume{}
+	for _, volume := range volumes {
+		if volume.Name == name {
+			candidates = append(candidates, volume)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// Ambiguous returns whether a name without prefix matches the volumes of
+// several engines, which Get doesn't pick from.
+func (volumes Volumes) Ambiguous(name string) bool {
+	count := 0
+	for _, volume := range volumes {
+		if volume.Name == name {
+			count++
+		}
+	}
+	return count > 1
+}
+
+// VolumeCreateRequest is the body of a volume creation.
+type VolumeCreateRequest struct {
+	Name       string
+	Driver     string
+	DriverOpts map[string]string
+	Labels     map[string]string `json:",omitempty"`
+}
+
+// Placement removes the placement of the volume from the request, and
+// returns it as the config the node of the volume is selected with. The
+// constraints and affinities are either driver options (ex. docker volume
+// create --opt constraint:storage==ssd) or labels, as for containers. A name
+// prefixed with the name of a node (ex. node-1/data) selects this node.
+func (r *VolumeCreateRequest) Placement() *ContainerConfig {
+	env := []string{}
+	opts := make(map[string]string)
+	for key, value := range r.DriverOpts {
+		// The client splits the option on its first '=', which is the
+		// one of the expression's operator.
+		if ok, k, _ := parseEnv(key); ok && (k == "constraint" || k == "affinity") {
+			env = append(env, key+"="+value)
+		} else {
+			opts[key] = value
+		}
+	}
+	sort.Strings(env)
+	r.DriverOpts = opts
+
+	if i := strings.Index(r.Name, "/"); i >= 0 {
+		env = append(env, "constraint:node=="+r.Name[:i])
+		r.Name = r.Name[i+1:]
+	}
+
+	labels := make(map[string]string)
+	for key, value := range r.Labels {
+		if key == SwarmLabelNamespace+".constraints" || key == SwarmLabelNamespace+".affinities" {
+			labels[key] = value
+			delete(r.Labels, key)
+		}
+	}
+
+	return BuildContainerConfig(dockerclient.ContainerConfig{Env: env, Labels: labels})
+}
@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVolumesGet(t *testing.T) {
+	node1 := &Engine{ID: "id-1", Name: "node-1"}
+	node2 := &Engine{ID: "id-2", Name: "node-2"}
+	volumes := Volumes{
+		{Volume: dockerclient.Volume{Name: "data"}, Engine: node1},
+		{Volume: dockerclient.Volume{Name: "data"}, Engine: node2},
+		{Volume: dockerclient.Volume{Name: "logs"}, Engine: node2},
+	}
+
+	assert.Equal(t, volumes.Get("node-1/data"), volumes[0])
+	assert.Equal(t, volumes.Get("id-2/data"), volumes[1])
+	assert.Equal(t, volumes.Get("logs"), volumes[2])
+	assert.Equal(t, volumes.Get("node-2/logs"), volumes[2])
+
+	// The name is ambiguous without the node.
+	assert.Nil(t, volumes.Get("data"))
+	assert.Nil(t, volumes.Get("node-1/logs"))
+	assert.Nil(t, volumes.Get(""))
+
+	assert.True(t, volumes.Ambiguous("data"))
+	assert.False(t, volumes.Ambiguous("logs"))
+	assert.False(t, volumes.Ambiguous("node-1/data"))
+	assert.False(t, volumes.Ambiguous("missing"))
+}
+
+func TestVolumePlacement(t *testing.T) {
+	request := &VolumeCreateRequest{
+		Name:   "node-1/data",
+		Driver: "local",
+		DriverOpts: map[string]string{
+			"constraint:storage": "=ssd",
+			"affinity:container": "=~db",
+			"size":               "10g",
+		},
+		Labels: map[string]string{
+			"com.docker.swarm.constraints": `["zone==eu"]`,
+			"owner":                        "ops",
+		},
+	}
+
+	config := request.Placement()
+	assert.Equal(t, config.Constraints(), []string{"zone==eu", "storage==ssd", "node==node-1"})
+	assert.Equal(t, config.Affinities(), []string{"container==~db"})
+
+	// The placement isn't sent to the engine.
+	assert.Equal(t, request.Name, "data")
+	assert.Equal(t, request.DriverOpts, map[string]string{"size": "10g"})
+	assert.Equal(t, request.Labels, map[string]string{"owner": "ops"})
+}
+
+func TestCreateRemoveVolume(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/volumes/create":
+			var request VolumeCreateRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(&dockerclient.Volume{Name: request.Name, Driver: request.Driver, Mountpoint: "/var/lib/docker/volumes/" + request.Name})
+		case r.Method == "DELETE" && r.URL.Path == "/volumes/data":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusConflict)
+			io.WriteString(w, "volume is in use")
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(server.Listener.Addr().String(), 0)
+	client := mockclient.NewMockClient()
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	engine.client = client
+
+	volume, err := engine.CreateVolume(&VolumeCreateRequest{Name: "data", Driver: "local"})
+	assert.NoError(t, err)
+	assert.Equal(t, volume.Name, "data")
+	assert.Equal(t, volume.Mountpoint, "/var/lib/docker/volumes/data")
+	assert.Equal(t, volume.Engine, engine)
+
+	assert.NoError(t, engine.RemoveVolume("data"))
+	err = engine.RemoveVolume("logs")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "volume is in use")
+}
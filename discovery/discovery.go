@@ -25,9 +25,10 @@ func NewEntry(url string) (*Entry, error) {
 	return &Entry{host, port}, nil
 }
 
-// String returns the string form of an entry.
+// String returns the string form of an entry. IPv6 hosts are enclosed in
+// square brackets (e.g. [::1]:2375).
 func (e *Entry) String() string {
-	return fmt.Sprintf("%s:%s", e.Host, e.Port)
+	return net.JoinHostPort(e.Host, e.Port)
 }
 
 // Equals returns true if cmp contains the same data.
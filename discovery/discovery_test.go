@@ -14,6 +14,19 @@ func TestNewEntry(t *testing.T) {
 
 	_, err = NewEntry("127.0.0.1")
 	assert.Error(t, err)
+
+	entry, err = NewEntry("[2001:db8::1]:2375")
+	assert.NoError(t, err)
+	assert.True(t, entry.Equals(&Entry{Host: "2001:db8::1", Port: "2375"}))
+	assert.Equal(t, entry.String(), "[2001:db8::1]:2375")
+
+	entry, err = NewEntry("hostname:2375")
+	assert.NoError(t, err)
+	assert.True(t, entry.Equals(&Entry{Host: "hostname", Port: "2375"}))
+	assert.Equal(t, entry.String(), "hostname:2375")
+
+	_, err = NewEntry("2001:db8::1:2375")
+	assert.Error(t, err)
 }
 
 func TestParse(t *testing.T) {
@@ -53,6 +66,14 @@ func TestCreateEntries(t *testing.T) {
 
 	_, err = CreateEntries([]string{"127.0.0.1", "127.0.0.2"})
 	assert.Error(t, err)
+
+	entries, err = CreateEntries([]string{"[::1]:2375", "[2001:db8::2]:2375"})
+	assert.NoError(t, err)
+	expected = Entries{
+		&Entry{Host: "::1", Port: "2375"},
+		&Entry{Host: "2001:db8::2", Port: "2375"},
+	}
+	assert.True(t, entries.Equals(expected))
 }
 
 func TestContainsEntry(t *testing.T) {
@@ -35,6 +35,25 @@ func TestContent(t *testing.T) {
 	assert.Equal(t, ips[4], "2.2.2.4:2222")
 }
 
+func TestContentIPv6(t *testing.T) {
+	data := `
+[::1]:1111
+[2001:db8::[1:2]]:2222 # inline comment
+hostname:3333
+`
+	ips := parseFileContent([]byte(data))
+	assert.Len(t, ips, 4)
+	assert.Equal(t, ips[0], "[::1]:1111")
+	assert.Equal(t, ips[1], "[2001:db8::1]:2222")
+	assert.Equal(t, ips[2], "[2001:db8::2]:2222")
+	assert.Equal(t, ips[3], "hostname:3333")
+
+	entries, err := discovery.CreateEntries(ips)
+	assert.NoError(t, err)
+	assert.Equal(t, entries[0].Host, "::1")
+	assert.Equal(t, entries[1].String(), "[2001:db8::1]:2222")
+}
+
 func TestRegister(t *testing.T) {
 	discovery := &Discovery{path: "/path/to/file"}
 	assert.Error(t, discovery.Register("0.0.0.0"))
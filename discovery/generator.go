@@ -4,26 +4,35 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
+// rangeRegexp matches a numeric range such as [1:11]. Only digits are
+// accepted on both sides so that bracketed IPv6 hosts ([::1]:2375) are not
+// mistaken for a range.
+var rangeRegexp = regexp.MustCompile(`\[([0-9]+):([0-9]+)\]`)
+
 // Generate takes care of IP generation
 func Generate(pattern string) []string {
-	re, _ := regexp.Compile(`\[(.+):(.+)\]`)
-	submatch := re.FindStringSubmatch(pattern)
-	if submatch == nil {
+	loc := rangeRegexp.FindStringSubmatchIndex(pattern)
+	if loc == nil {
 		return []string{pattern}
 	}
 
-	from, err := strconv.Atoi(submatch[1])
+	from, err := strconv.Atoi(pattern[loc[2]:loc[3]])
 	if err != nil {
 		return []string{pattern}
 	}
-	to, err := strconv.Atoi(submatch[2])
+	to, err := strconv.Atoi(pattern[loc[4]:loc[5]])
 	if err != nil {
 		return []string{pattern}
 	}
 
-	template := re.ReplaceAllString(pattern, "%d")
+	// Only the first range is expanded. Escape any literal '%' so the rest
+	// of the pattern survives Sprintf untouched.
+	prefix := strings.Replace(pattern[:loc[0]], "%", "%%", -1)
+	suffix := strings.Replace(pattern[loc[1]:], "%", "%%", -1)
+	template := prefix + "%d" + suffix
 
 	var result []string
 	for val := from; val <= to; val++ {
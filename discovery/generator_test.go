@@ -53,3 +53,30 @@ func TestGenerateWithMalformedInputAtRangeEnd(t *testing.T) {
 	assert.Equal(t, len(ips), 1)
 	assert.Equal(t, ips[0], malformedInput)
 }
+
+func TestGenerateIPv6NotGenerate(t *testing.T) {
+	ips := Generate("[::1]:2375")
+	assert.Equal(t, len(ips), 1)
+	assert.Equal(t, ips[0], "[::1]:2375")
+
+	ips = Generate("[2001:db8::1]:2375")
+	assert.Equal(t, len(ips), 1)
+	assert.Equal(t, ips[0], "[2001:db8::1]:2375")
+
+	ips = Generate("[fe80::1%eth0]:2375")
+	assert.Equal(t, len(ips), 1)
+	assert.Equal(t, ips[0], "[fe80::1%eth0]:2375")
+}
+
+func TestGenerateIPv6WithRange(t *testing.T) {
+	ips := Generate("[2001:db8::[1:3]]:2375")
+	assert.Equal(t, len(ips), 3)
+	assert.Equal(t, ips[0], "[2001:db8::1]:2375")
+	assert.Equal(t, ips[1], "[2001:db8::2]:2375")
+	assert.Equal(t, ips[2], "[2001:db8::3]:2375")
+
+	ips = Generate("[fe80::[1:2]%eth0]:2375")
+	assert.Equal(t, len(ips), 2)
+	assert.Equal(t, ips[0], "[fe80::1%eth0]:2375")
+	assert.Equal(t, ips[1], "[fe80::2%eth0]:2375")
+}
@@ -103,6 +103,12 @@ func TestWatch(t *testing.T) {
 	mockCh <- kvs
 	assert.Equal(t, <-ch, expected)
 
+	// Add an IPv6 entry.
+	expected = append(expected, &discovery.Entry{Host: "2001:db8::4", Port: "4444"})
+	kvs = append(kvs, &store.KVPair{Key: path.Join("path", discoveryPath, "[2001:db8::4]:4444"), Value: []byte("[2001:db8::4]:4444")})
+	mockCh <- kvs
+	assert.Equal(t, <-ch, expected)
+
 	// Make sure that if an error occurs it retries.
 	// This third call to WatchTree will be checked later by AssertExpectations.
 	s.On("WatchTree", "path/"+discoveryPath, mock.Anything).Return(mockCh, nil)
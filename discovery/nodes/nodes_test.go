@@ -26,6 +26,17 @@ func TestInitializeWithPattern(t *testing.T) {
 	assert.Equal(t, d.entries[4].String(), "2.2.2.4:2222")
 }
 
+func TestInitializeIPv6(t *testing.T) {
+	d := &Discovery{}
+	assert.NoError(t, d.Initialize("[::1]:1111,[2001:db8::[1:2]]:2222,hostname:3333", 0, 0))
+	assert.Equal(t, len(d.entries), 4)
+	assert.Equal(t, d.entries[0].String(), "[::1]:1111")
+	assert.Equal(t, d.entries[1].String(), "[2001:db8::1]:2222")
+	assert.Equal(t, d.entries[2].String(), "[2001:db8::2]:2222")
+	assert.Equal(t, d.entries[3].String(), "hostname:3333")
+	assert.Equal(t, d.entries[1].Host, "2001:db8::1")
+}
+
 func TestWatch(t *testing.T) {
 	d := &Discovery{}
 	d.Initialize("1.1.1.1:1111,2.2.2.2:2222", 0, 0)
@@ -1,6 +1,9 @@
 package token
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -46,3 +49,21 @@ func TestRegister(t *testing.T) {
 
 	assert.NoError(t, d.Register(expected))
 }
+
+func TestFetchIPv6(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/clusters/TEST_TOKEN")
+		json.NewEncoder(w).Encode([]string{"[2001:db8::1]:2375", "hostname:2375"})
+	}))
+	defer ts.Close()
+
+	d := &Discovery{token: "TEST_TOKEN", url: ts.URL}
+	entries, err := d.fetch()
+	assert.NoError(t, err)
+	expected := discovery.Entries{
+		&discovery.Entry{Host: "2001:db8::1", Port: "2375"},
+		&discovery.Entry{Host: "hostname", Port: "2375"},
+	}
+	assert.True(t, entries.Equals(expected))
+	assert.Equal(t, entries[0].String(), "[2001:db8::1]:2375")
+}
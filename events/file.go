@@ -0,0 +1,115 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/docker/docker/pkg/units"
+)
+
+// fileWriter appends events as JSON lines to a file, rotating it once it
+// reaches its maximum size.
+type fileWriter struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	file *os.File
+	size int64
+}
+
+func newFileWriter(path string, opts map[string]string) (*fileWriter, error) {
+	if path == "" {
+		return nil, fmt.Errorf("missing path for the file event sink")
+	}
+
+	w := &fileWriter{path: path, maxSize: 10 * 1024 * 1024}
+	if v, ok := opts["max-size"]; ok {
+		size, err := units.RAMInBytes(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for max-size", v)
+		}
+		w.maxSize = size
+	}
+	var err error
+	if w.maxFiles, err = intOption(opts, "max-files", 5); err != nil {
+		return nil, err
+	}
+	if w.maxFiles < 1 {
+		return nil, fmt.Errorf("invalid value %d for max-files", w.maxFiles)
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *fileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write appends the messages to the file, one per line.
+func (w *fileWriter) Write(messages []Message) error {
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+
+		if w.size > 0 && w.size+int64(len(data)) > w.maxSize {
+			if err := w.rotate(); err != nil {
+				return err
+			}
+		}
+
+		n, err := w.file.Write(data)
+		w.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotate renames path to path.1, path.1 to path.2 and so on, keeping at most
+// maxFiles files, and starts a new file.
+func (w *fileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	for i := w.maxFiles - 1; i > 0; i-- {
+		from := w.path
+		if i > 1 {
+			from = fmt.Sprintf("%s.%d", w.path, i-1)
+		}
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", w.path, i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if w.maxFiles == 1 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return w.open()
+}
+
+func (w *fileWriter) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"strings"
+
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/swarm/cluster"
+)
+
+// Attributes are the properties of an event, besides its message, that
+// filters select on.
+type Attributes struct {
+	ContainerName string            `json:",omitempty"`
+	SwarmID       string            `json:",omitempty"`
+	Image         string            `json:",omitempty"`
+	Labels        map[string]string `json:",omitempty"`
+}
+
+// NewAttributes extracts the attributes of a cluster event.
+func NewAttributes(e *cluster.Event) Attributes {
+	a := Attributes{}
+
+	// Container events come from their image, image events refer to it.
+	if e.From != "swarm" {
+		a.Image = e.From
+	}
+	if e.From == "" && e.Container == nil {
+		a.Image = e.Id
+	}
+
+	if container := e.Container; container != nil {
+		if len(container.Names) > 0 {
+			a.ContainerName = strings.TrimPrefix(container.Names[0], "/")
+		}
+		if container.Config != nil {
+			a.SwarmID = container.Config.SwarmID()
+			a.Labels = container.Config.Labels
+			if a.Image == "" {
+				a.Image = container.Config.Image
+			}
+		}
+	}
+
+	return a
+}
+
+// Filter selects events with docker style filters: container (ID, Swarm ID
+// or name), image, event, node (name or ID) and label (key or key=value).
+type Filter map[string][]string
+
+// Match returns whether an event is selected by every filter.
+func (f Filter) Match(m Message, a Attributes) bool {
+	if !matchAny(f["event"], m.Status) {
+		return false
+	}
+	if !matchAny(f["node"], m.Node.Name, m.Node.ID) {
+		return false
+	}
+	if !matchAny(f["container"], m.ID, stringid.TruncateID(m.ID), a.SwarmID, stringid.TruncateID(a.SwarmID), a.ContainerName) {
+		return false
+	}
+	if !matchAny(f["image"], a.Image, repository(a.Image)) {
+		return false
+	}
+	for _, label := range f["label"] {
+		kv := strings.SplitN(label, "=", 2)
+		value, ok := a.Labels[kv[0]]
+		if !ok || (len(kv) == 2 && value != kv[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// repository returns the name of an image without its tag. The name may have
+// a registry with a port, ex. localhost:5000/app:v1.
+func repository(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// matchAny returns true if there are no values or if one of the sources is
+// one of the values.
+func matchAny(values []string, sources ...string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		for _, source := range sources {
+			if source != "" && source == value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEvent(status, id, from string) *cluster.Event {
+	event := &cluster.Event{
+		Engine: &cluster.Engine{
+			ID:   "node_id",
+			Name: "node_name",
+			IP:   "node_ip",
+			Addr: "node_addr",
+		},
+	}
+
+	event.Event.Status = status
+	event.Event.Id = id
+	event.Event.From = from
+	return event
+}
+
+func TestFilter(t *testing.T) {
+	config := cluster.BuildContainerConfig(dockerclient.ContainerConfig{
+		Image:  "redis:3.0",
+		Labels: map[string]string{"env": "prod", "com.docker.swarm.id": "7f1b0a2c9e4d8b6a5f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a"},
+	})
+	event := newTestEvent("start", "4c01db0b339c4c01db0b339c4c01db0b339c4c01db0b339c4c01db0b339c4c01", "redis:3.0")
+	event.Container = &cluster.Container{
+		Container: dockerclient.Container{Id: event.Id, Names: []string{"/cache"}},
+		Config:    config,
+	}
+	message, attributes := NewMessage(event), NewAttributes(event)
+
+	matching := []Filter{
+		{},
+		{"event": {"start"}},
+		{"event": {"die", "start"}},
+		{"node": {"node_name"}},
+		{"node": {"node_id"}},
+		{"container": {"cache"}},
+		{"container": {"4c01db0b339c"}},
+		{"container": {event.Id}},
+		{"container": {"7f1b0a2c9e4d"}},
+		{"image": {"redis"}},
+		{"image": {"redis:3.0"}},
+		{"label": {"env"}},
+		{"label": {"env=prod"}},
+		{"event": {"start"}, "image": {"redis"}, "label": {"env=prod"}},
+	}
+	for _, filter := range matching {
+		assert.True(t, filter.Match(message, attributes), fmt.Sprintf("%v", filter))
+	}
+
+	notMatching := []Filter{
+		{"event": {"die"}},
+		{"node": {"other"}},
+		{"container": {"other"}},
+		{"container": {"4c01"}},
+		{"image": {"redis:2.8"}},
+		{"label": {"env=dev"}},
+		{"label": {"env", "tier"}},
+		{"event": {"start"}, "image": {"nginx"}},
+	}
+	for _, filter := range notMatching {
+		assert.False(t, filter.Match(message, attributes), fmt.Sprintf("%v", filter))
+	}
+
+	// Images of a registry with a port match by repository.
+	event = newTestEvent("start", "4c01db0b339c", "localhost:5000/app:v1")
+	event.Container = &cluster.Container{
+		Container: dockerclient.Container{Id: event.Id},
+		Config:    cluster.BuildContainerConfig(dockerclient.ContainerConfig{Image: "localhost:5000/app:v1"}),
+	}
+	assert.True(t, Filter{"image": {"localhost:5000/app"}}.Match(NewMessage(event), NewAttributes(event)))
+	assert.True(t, Filter{"image": {"localhost:5000/app:v1"}}.Match(NewMessage(event), NewAttributes(event)))
+	assert.False(t, Filter{"image": {"localhost"}}.Match(NewMessage(event), NewAttributes(event)))
+
+	// Image events refer to the image by ID.
+	event = newTestEvent("pull", "redis:3.0", "")
+	assert.True(t, Filter{"image": {"redis"}}.Match(NewMessage(event), NewAttributes(event)))
+
+	// Engine events only match engine filters.
+	event = newTestEvent("engine_disconnect", "", "swarm")
+	assert.True(t, Filter{"event": {"engine_disconnect"}, "node": {"node_name"}}.Match(NewMessage(event), NewAttributes(event)))
+	assert.False(t, Filter{"image": {"swarm"}}.Match(NewMessage(event), NewAttributes(event)))
+}
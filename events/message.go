@@ -0,0 +1,41 @@
+package events
+
+import "github.com/docker/swarm/cluster"
+
+// Node is the node an event comes from.
+type Node struct {
+	Name string `json:"Name"`
+	ID   string `json:"Id"`
+	Addr string `json:"Addr"`
+	IP   string `json:"Ip"`
+}
+
+// Message is the JSON representation of a cluster event, as sent to API
+// clients and event sinks.
+type Message struct {
+	Status string `json:"status"`
+	ID     string `json:"id"`
+	From   string `json:"from"`
+	Time   int64  `json:"time"`
+	Node   Node   `json:"node"`
+
+	// engine_update events also list what changed on the engine.
+	Changes []string `json:"changes,omitempty"`
+}
+
+// NewMessage creates the message of a cluster event.
+func NewMessage(e *cluster.Event) Message {
+	return Message{
+		Status: e.Status,
+		ID:     e.Id,
+		From:   e.From + " node:" + e.Engine.Name,
+		Time:   e.Time,
+		Node: Node{
+			Name: e.Engine.Name,
+			ID:   e.Engine.ID,
+			Addr: e.Engine.Addr,
+			IP:   e.Engine.IP,
+		},
+		Changes: e.Changes,
+	}
+}
@@ -0,0 +1,217 @@
+package events
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/metrics"
+)
+
+// Number of events queued for a sink before new ones are dropped.
+const sinkQueueSize = 1000
+
+// filterKeys are the sink options that are filters.
+var filterKeys = map[string]bool{
+	"container": true,
+	"event":     true,
+	"image":     true,
+	"label":     true,
+	"node":      true,
+}
+
+// writer delivers batches of messages to a destination.
+type writer interface {
+	Write(messages []Message) error
+	Close() error
+}
+
+// Sink sends the cluster events matching its filter to a destination. Events
+// are queued so that a slow destination never blocks the cluster.
+type Sink struct {
+	name   string
+	filter Filter
+	writer writer
+
+	batchSize     int
+	flushInterval time.Duration
+
+	queue chan Message
+	done  chan struct{}
+}
+
+func newSink(name string, filter Filter, w writer, batchSize int, flushInterval time.Duration) *Sink {
+	s := &Sink{
+		name:          name,
+		filter:        filter,
+		writer:        w,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		queue:         make(chan Message, sinkQueueSize),
+		done:          make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// NewSink creates a sink from its description: type:target[,option=value...].
+// The supported types are webhook, syslog and file. The container, event,
+// image, label and node options are filters and can be repeated.
+//
+//	webhook:https://hooks.example.com/swarm,event=engine_disconnect,batch=10,retries=3
+//	syslog:udp://10.0.0.1:514,tag=swarm
+//	file:/var/log/swarm/events.json,max-size=10MB,max-files=5
+func NewSink(description string) (*Sink, error) {
+	parts := strings.SplitN(description, ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid event sink %q, expected type:target[,option=value...]", description)
+	}
+	kind := parts[0]
+	options := strings.Split(parts[1], ",")
+	target := options[0]
+
+	filter := Filter{}
+	opts := make(map[string]string)
+	for _, option := range options[1:] {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid option %q for event sink %s", option, kind)
+		}
+		if filterKeys[kv[0]] {
+			filter[kv[0]] = append(filter[kv[0]], kv[1])
+		} else {
+			opts[kv[0]] = kv[1]
+		}
+	}
+
+	// The options are checked before creating the writer, which may open a
+	// file or a connection.
+	batchSize := 1
+	if kind == "webhook" {
+		batchSize = 10
+	}
+	batchSize, err := intOption(opts, "batch", batchSize)
+	if err != nil {
+		return nil, err
+	}
+	if batchSize < 1 {
+		return nil, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	flushInterval, err := durationOption(opts, "flush-interval", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if flushInterval <= 0 {
+		return nil, fmt.Errorf("invalid flush interval %s", flushInterval)
+	}
+
+	var w writer
+	switch kind {
+	case "webhook":
+		w, err = newWebhookWriter(target, opts)
+	case "syslog":
+		w, err = newSyslogWriter(target, opts)
+	case "file":
+		w, err = newFileWriter(target, opts)
+	default:
+		return nil, fmt.Errorf("unknown event sink type %q", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newSink(kind+":"+target, filter, w, batchSize, flushInterval), nil
+}
+
+// Handle queues the event if it matches the filter of the sink.
+func (s *Sink) Handle(e *cluster.Event) error {
+	message := NewMessage(e)
+	if !s.filter.Match(message, NewAttributes(e)) {
+		return nil
+	}
+
+	select {
+	case s.queue <- message:
+	default:
+		log.WithField("sink", s.name).Warn("Event sink queue is full, dropping event")
+	}
+	return nil
+}
+
+// Collect returns the depth of the queue of the sink.
+func (s *Sink) Collect() []*metrics.Family {
+	return []*metrics.Family{
+		{
+			Name:    "swarm_events_queue_depth",
+			Help:    "Number of events waiting to be delivered, by consumer.",
+			Type:    metrics.TypeGauge,
+			Samples: []metrics.Sample{{Labels: []metrics.Label{{Name: "consumer", Value: s.name}}, Value: float64(len(s.queue))}},
+		},
+	}
+}
+
+// Close delivers the queued events and closes the sink.
+func (s *Sink) Close() error {
+	close(s.queue)
+	<-s.done
+	return s.writer.Close()
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Message, 0, s.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.writer.Write(batch); err != nil {
+			log.WithField("sink", s.name).Errorf("Unable to deliver %d events: %v", len(batch), err)
+		}
+		batch = make([]Message, 0, s.batchSize)
+	}
+
+	for {
+		select {
+		case message, ok := <-s.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, message)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func intOption(opts map[string]string, key string, value int) (int, error) {
+	if v, ok := opts[key]; ok {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q for %s", v, key)
+		}
+		return i, nil
+	}
+	return value, nil
+}
+
+func durationOption(opts map[string]string, key string, value time.Duration) (time.Duration, error) {
+	if v, ok := opts[key]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q for %s", v, key)
+		}
+		return d, nil
+	}
+	return value, nil
+}
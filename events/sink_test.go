@@ -0,0 +1,156 @@
+package events
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSinkInvalid(t *testing.T) {
+	for _, description := range []string{
+		"",
+		"webhook",
+		"unknown:foo",
+		"webhook:ftp://example.com",
+		"webhook:http://example.com,retries",
+		"webhook:http://example.com,retries=x",
+		"webhook:http://example.com,batch=0",
+		"webhook:http://example.com,flush-interval=1",
+		"webhook:http://example.com,flush-interval=0",
+		"webhook:http://example.com,flush-interval=0s",
+		"webhook:http://example.com,flush-interval=-1s",
+		"webhook:http://example.com,retry-delay=-1s",
+		"webhook:http://example.com,timeout=-1s",
+		"syslog:ftp://example.com",
+		"file:",
+		"file:/tmp/events.json,max-size=huge",
+		"file:/tmp/events.json,max-files=0",
+	} {
+		_, err := NewSink(description)
+		assert.Error(t, err, description)
+	}
+}
+
+func TestNewSinkInvalidOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.json")
+
+	// The file isn't opened when the options are invalid.
+	for _, option := range []string{"batch=0", "flush-interval=0"} {
+		_, err := NewSink("file:" + path + "," + option)
+		assert.Error(t, err, option)
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err), option)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		batches  [][]Message
+		attempts int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		// Fail the first delivery to exercise the retries.
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var batch []Message
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink("webhook:" + server.URL + ",event=engine_disconnect,event=engine_reconnect,batch=2,retry-delay=1ms,flush-interval=1h")
+	assert.NoError(t, err)
+
+	assert.NoError(t, sink.Handle(newTestEvent("engine_disconnect", "", "swarm")))
+	assert.NoError(t, sink.Handle(newTestEvent("start", "id", "redis")))
+	assert.NoError(t, sink.Handle(newTestEvent("engine_reconnect", "", "swarm")))
+	assert.NoError(t, sink.Handle(newTestEvent("engine_disconnect", "", "swarm")))
+	assert.NoError(t, sink.Close())
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, attempts, 3)
+	assert.Len(t, batches, 2)
+	assert.Len(t, batches[0], 2)
+	assert.Equal(t, batches[0][0].Status, "engine_disconnect")
+	assert.Equal(t, batches[0][1].Status, "engine_reconnect")
+	assert.Equal(t, batches[0][1].Node.Name, "node_name")
+	assert.Len(t, batches[1], 1)
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.json")
+
+	// Every message is about 150 bytes, only one fits per file.
+	sink, err := NewSink("file:" + path + ",max-size=200b,max-files=2,node=node_name")
+	assert.NoError(t, err)
+
+	assert.NoError(t, sink.Handle(newTestEvent("create", "id1", "redis")))
+	assert.NoError(t, sink.Handle(newTestEvent("start", "id1", "redis")))
+	assert.NoError(t, sink.Handle(newTestEvent("die", "id1", "redis")))
+	event := newTestEvent("destroy", "id1", "redis")
+	event.Engine.Name = "other"
+	assert.NoError(t, sink.Handle(event))
+	assert.NoError(t, sink.Close())
+
+	files, err := filepath.Glob(path + "*")
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 1)
+	var message Message
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &message))
+	assert.Equal(t, message.Status, "die")
+
+	data, err = ioutil.ReadFile(path + ".1")
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"status":"start"`)
+}
+
+func TestSinkFlushInterval(t *testing.T) {
+	received := make(chan []Message, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []Message
+		json.NewDecoder(r.Body).Decode(&batch)
+		received <- batch
+	}))
+	defer server.Close()
+
+	sink, err := NewSink("webhook:" + server.URL + ",batch=100,flush-interval=10ms")
+	assert.NoError(t, err)
+	defer sink.Close()
+
+	assert.NoError(t, sink.Handle(newTestEvent("engine_disconnect", "", "swarm")))
+	select {
+	case batch := <-received:
+		assert.Len(t, batch, 1)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch was not flushed")
+	}
+}
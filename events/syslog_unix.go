@@ -0,0 +1,59 @@
+// +build !windows
+
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/url"
+)
+
+// syslogWriter sends events as JSON to syslog, either the local daemon or a
+// remote one (udp://host:port or tcp://host:port).
+type syslogWriter struct {
+	writer *syslog.Writer
+}
+
+func newSyslogWriter(target string, opts map[string]string) (*syslogWriter, error) {
+	network, raddr := "", ""
+	if target != "" {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "udp" && u.Scheme != "tcp" {
+			return nil, fmt.Errorf("invalid syslog address %q", target)
+		}
+		network, raddr = u.Scheme, u.Host
+	}
+
+	tag := "swarm"
+	if v, ok := opts["tag"]; ok {
+		tag = v
+	}
+
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{writer: w}, nil
+}
+
+// Write sends every message as a syslog entry.
+func (w *syslogWriter) Write(messages []Message) error {
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if err := w.writer.Info(string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *syslogWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,9 @@
+// +build windows
+
+package events
+
+import "errors"
+
+func newSyslogWriter(target string, opts map[string]string) (writer, error) {
+	return nil, errors.New("the syslog event sink is not supported on windows")
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhookWriter POSTs batches of events as a JSON array to a URL, retrying
+// failed deliveries.
+type webhookWriter struct {
+	url        string
+	client     *http.Client
+	retries    int
+	retryDelay time.Duration
+}
+
+func newWebhookWriter(target string, opts map[string]string) (*webhookWriter, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid webhook URL %q", target)
+	}
+
+	w := &webhookWriter{url: target}
+	if w.retries, err = intOption(opts, "retries", 3); err != nil {
+		return nil, err
+	}
+	if w.retryDelay, err = durationOption(opts, "retry-delay", time.Second); err != nil {
+		return nil, err
+	}
+	if w.retryDelay < 0 {
+		return nil, fmt.Errorf("invalid retry delay %s", w.retryDelay)
+	}
+	timeout, err := durationOption(opts, "timeout", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if timeout < 0 {
+		return nil, fmt.Errorf("invalid timeout %s", timeout)
+	}
+	w.client = &http.Client{Timeout: timeout}
+
+	return w, nil
+}
+
+// Write delivers the messages, retrying with an exponential backoff.
+func (w *webhookWriter) Write(messages []Message) error {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+
+	delay := w.retryDelay
+	for attempt := 0; ; attempt++ {
+		if err = w.post(data); err == nil || attempt >= w.retries {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (w *webhookWriter) post(data []byte) error {
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", w.url, resp.Status)
+	}
+	return nil
+}
+
+func (w *webhookWriter) Close() error {
+	return nil
+}
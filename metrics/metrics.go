@@ -0,0 +1,155 @@
+// Package metrics implements the metrics of the manager, exposed in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Label is a label of a sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a single value of a metric family.
+type Sample struct {
+	// Suffix is appended to the family name (ex. _bucket for histograms).
+	Suffix string
+	Labels []Label
+	Value  float64
+}
+
+// Family is a set of samples sharing a name, a help and a type.
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Collector returns metric families when the metrics are scraped.
+type Collector interface {
+	Collect() []*Family
+}
+
+// Registry is a set of collectors.
+type Registry struct {
+	sync.Mutex
+	collectors []Collector
+}
+
+// DefaultRegistry is the registry of the process wide metrics.
+var DefaultRegistry = &Registry{}
+
+// Register adds a collector to the default registry.
+func Register(c Collector) {
+	DefaultRegistry.Register(c)
+}
+
+// Register adds a collector to the registry.
+func (r *Registry) Register(c Collector) {
+	r.Lock()
+	r.collectors = append(r.collectors, c)
+	r.Unlock()
+}
+
+// Gather collects the families of every collector of the registry.
+func (r *Registry) Gather() []*Family {
+	r.Lock()
+	collectors := append([]Collector{}, r.collectors...)
+	r.Unlock()
+
+	var families []*Family
+	for _, c := range collectors {
+		families = append(families, c.Collect()...)
+	}
+	return families
+}
+
+// WriteText writes families in the text exposition format. Families sharing
+// a name are merged and families are sorted by name.
+func WriteText(w io.Writer, families []*Family) error {
+	merged := make(map[string]*Family)
+	names := []string{}
+	for _, f := range families {
+		if m, ok := merged[f.Name]; ok {
+			m.Samples = append(m.Samples, f.Samples...)
+			continue
+		}
+		merged[f.Name] = &Family{Name: f.Name, Help: f.Help, Type: f.Type, Samples: append([]Sample{}, f.Samples...)}
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := merged[name]
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.Name, escapeHelp(f.Help), f.Name, f.Type); err != nil {
+			return err
+		}
+		for _, s := range f.Samples {
+			if _, err := fmt.Fprintf(w, "%s%s%s %s\n", f.Name, s.Suffix, formatLabels(s.Labels), formatValue(s.Value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", l.Name, escapeLabelValue(l.Value)))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+// makeLabels pairs label names with their values.
+func makeLabels(names, values []string) []Label {
+	labels := make([]Label, len(names))
+	for i, name := range names {
+		labels[i] = Label{Name: name, Value: values[i]}
+	}
+	return labels
+}
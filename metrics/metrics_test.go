@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteText(t *testing.T) {
+	families := []*Family{
+		{Name: "b", Help: "B.", Type: TypeGauge, Samples: []Sample{{Value: 1}}},
+		{Name: "a", Help: "A\nhelp.", Type: TypeGauge, Samples: []Sample{{Labels: []Label{{Name: "x", Value: `say "hi"`}}, Value: 0.5}}},
+		{Name: "b", Help: "B.", Type: TypeGauge, Samples: []Sample{{Labels: []Label{{Name: "y", Value: "z"}}, Value: 2}}},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteText(&buf, families))
+	assert.Equal(t, `# HELP a A\nhelp.
+# TYPE a gauge
+a{x="say \"hi\""} 0.5
+# HELP b B.
+# TYPE b gauge
+b 1
+b{y="z"} 2
+`, buf.String())
+}
+
+func TestCounterVec(t *testing.T) {
+	c := NewCounterVec("requests_total", "Requests.", "method")
+	c.Inc("GET")
+	c.Add(2, "GET")
+	c.Inc("POST")
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteText(&buf, c.Collect()))
+	assert.Equal(t, `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{method="GET"} 3
+requests_total{method="POST"} 1
+`, buf.String())
+
+	assert.Panics(t, func() { c.Inc() })
+}
+
+func TestHistogramVec(t *testing.T) {
+	h := NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	h.Observe(0.05, "/info")
+	h.Observe(0.5, "/info")
+	h.Observe(5, "/info")
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteText(&buf, h.Collect()))
+	assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/info",le="0.1"} 1
+latency_seconds_bucket{route="/info",le="1"} 2
+latency_seconds_bucket{route="/info",le="+Inf"} 3
+latency_seconds_sum{route="/info"} 5.55
+latency_seconds_count{route="/info"} 3
+`, buf.String())
+}
+
+func TestRegistry(t *testing.T) {
+	r := &Registry{}
+	r.Register(NewGaugeFunc("leader", "Leader.", func() float64 { return 1 }))
+	r.Register(NewCounterVec("empty_total", "Empty."))
+
+	families := r.Gather()
+	assert.Len(t, families, 2)
+	assert.Equal(t, "leader", families[0].Name)
+	assert.Equal(t, []Sample{{Value: 1}}, families[0].Samples)
+}
@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the default histogram buckets, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	sync.Mutex
+	name   string
+	help   string
+	labels []string
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// NewCounterVec creates a new counter with the given label names.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]*counterValue),
+	}
+}
+
+// Inc increments the counter of the given label values.
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v to the counter of the given label values.
+func (c *CounterVec) Add(v float64, values ...string) {
+	checkValues(c.name, c.labels, values)
+	key := strings.Join(values, "\xff")
+
+	c.Lock()
+	defer c.Unlock()
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labels: values}
+		c.values[key] = cv
+	}
+	cv.value += v
+}
+
+// Collect implements Collector.
+func (c *CounterVec) Collect() []*Family {
+	c.Lock()
+	defer c.Unlock()
+
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f := &Family{Name: c.name, Help: c.help, Type: TypeCounter}
+	for _, key := range keys {
+		cv := c.values[key]
+		f.Samples = append(f.Samples, Sample{Labels: makeLabels(c.labels, cv.labels), Value: cv.value})
+	}
+	return []*Family{f}
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	sync.Mutex
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec creates a new histogram with the given buckets and label
+// names.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+}
+
+// Observe adds an observation to the histogram of the given label values.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	checkValues(h.name, h.labels, values)
+	key := strings.Join(values, "\xff")
+
+	h.Lock()
+	defer h.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labels: values, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hv.counts[i]++
+		}
+	}
+	hv.count++
+	hv.sum += v
+}
+
+// Collect implements Collector.
+func (h *HistogramVec) Collect() []*Family {
+	h.Lock()
+	defer h.Unlock()
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f := &Family{Name: h.name, Help: h.help, Type: TypeHistogram}
+	for _, key := range keys {
+		hv := h.values[key]
+		labels := makeLabels(h.labels, hv.labels)
+		for i, upper := range h.buckets {
+			f.Samples = append(f.Samples, Sample{Suffix: "_bucket", Labels: withLabel(labels, "le", formatValue(upper)), Value: float64(hv.counts[i])})
+		}
+		f.Samples = append(f.Samples,
+			Sample{Suffix: "_bucket", Labels: withLabel(labels, "le", formatValue(math.Inf(1))), Value: float64(hv.count)},
+			Sample{Suffix: "_sum", Labels: labels, Value: hv.sum},
+			Sample{Suffix: "_count", Labels: labels, Value: float64(hv.count)},
+		)
+	}
+	return []*Family{f}
+}
+
+// GaugeFunc is a gauge whose value is computed when the metrics are scraped.
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewGaugeFunc creates a new gauge computed by fn.
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, fn: fn}
+}
+
+// Collect implements Collector.
+func (g *GaugeFunc) Collect() []*Family {
+	return []*Family{{Name: g.name, Help: g.help, Type: TypeGauge, Samples: []Sample{{Value: g.fn()}}}}
+}
+
+func withLabel(labels []Label, name, value string) []Label {
+	return append(append([]Label{}, labels...), Label{Name: name, Value: value})
+}
+
+func checkValues(name string, labels, values []string) {
+	if len(labels) != len(values) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", name, len(labels), len(values)))
+	}
+}
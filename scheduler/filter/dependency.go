@@ -40,6 +40,13 @@ func (f *DependencyFilter) Filter(config *cluster.ContainerConfig, nodes []*node
 		net = append(net, strings.TrimPrefix(config.HostConfig.NetworkMode, "container:"))
 	}
 
+	// Local networks only exist on their node.
+	if network := userNetwork(config); network != "" {
+		if withNetwork, ok := localNetworkNodes(network, nodes); ok {
+			nodes = withNetwork
+		}
+	}
+
 	candidates := []*node.Node{}
 	for _, node := range nodes {
 		if f.check(volumes, node) &&
@@ -65,7 +72,7 @@ func (f *DependencyFilter) String(config *cluster.ContainerConfig) string {
 	for _, link := range config.HostConfig.Links {
 		dependencies = append(dependencies, fmt.Sprintf("--link=%s", link))
 	}
-	if strings.HasPrefix(config.HostConfig.NetworkMode, "container:") {
+	if strings.HasPrefix(config.HostConfig.NetworkMode, "container:") || userNetwork(config) != "" {
 		dependencies = append(dependencies, fmt.Sprintf("--net=%s", config.HostConfig.NetworkMode))
 	}
 	return strings.Join(dependencies, " ")
@@ -80,3 +87,30 @@ func (f *DependencyFilter) check(dependencies []string, node *node.Node) bool {
 	}
 	return true
 }
+
+// userNetwork returns the user-defined network the container joins, "" for
+// the default networks and the network of another container.
+func userNetwork(config *cluster.ContainerConfig) string {
+	switch mode := config.HostConfig.NetworkMode; mode {
+	case "", "default", "bridge", "host", "none":
+		return ""
+	default:
+		if strings.HasPrefix(mode, "container:") {
+			return ""
+		}
+		return mode
+	}
+}
+
+// localNetworkNodes returns the nodes which have the local network `name`,
+// and false when no node has it: global and unknown networks don't restrict
+// the placement.
+func localNetworkNodes(name string, nodes []*node.Node) ([]*node.Node, bool) {
+	result := []*node.Node{}
+	for _, node := range nodes {
+		if network := node.Networks.Get(name); network != nil && network.IsLocal() {
+			result = append(result, node)
+		}
+	}
+	return result, len(result) > 0
+}
@@ -228,3 +228,58 @@ func TestDependencyFilterChaining(t *testing.T) {
 	result, err = f.Filter(config, nodes)
 	assert.Error(t, err)
 }
+
+func TestDependencyFilterNetworks(t *testing.T) {
+	var (
+		f     = DependencyFilter{}
+		node0 = &cluster.Engine{ID: "node-0-id", Name: "node-0-name"}
+		node1 = &cluster.Engine{ID: "node-1-id", Name: "node-1-name"}
+		nodes = []*node.Node{
+			{
+				ID:   "node-0-id",
+				Name: "node-0-name",
+				Addr: "node-0",
+				Networks: cluster.Networks{
+					{ID: "local", Name: "backend", Scope: "local", Engine: node0},
+					{ID: "overlay", Name: "frontend", Scope: "global", Engine: node0},
+				},
+			},
+			{
+				ID:   "node-1-id",
+				Name: "node-1-name",
+				Addr: "node-1",
+				Networks: cluster.Networks{
+					{ID: "overlay", Name: "frontend", Scope: "global", Engine: node1},
+				},
+			},
+		}
+		result []*node.Node
+		err    error
+	)
+
+	// Local networks are only on their node.
+	for _, network := range []string{"backend", "node-0-name/backend", "local"} {
+		config := &cluster.ContainerConfig{ContainerConfig: dockerclient.ContainerConfig{HostConfig: dockerclient.HostConfig{NetworkMode: network}}}
+		result, err = f.Filter(config, nodes)
+		assert.NoError(t, err)
+		assert.Equal(t, result, nodes[:1])
+	}
+
+	// Global, default and unknown networks don't restrict the placement.
+	for _, network := range []string{"frontend", "bridge", "host", "unknown"} {
+		config := &cluster.ContainerConfig{ContainerConfig: dockerclient.ContainerConfig{HostConfig: dockerclient.HostConfig{NetworkMode: network}}}
+		result, err = f.Filter(config, nodes)
+		assert.NoError(t, err)
+		assert.Equal(t, result, nodes)
+	}
+
+	// The network and the other dependencies must be on the same node.
+	nodes[1].Containers = []*cluster.Container{{Container: dockerclient.Container{Id: "c1"}}}
+	config := &cluster.ContainerConfig{ContainerConfig: dockerclient.ContainerConfig{HostConfig: dockerclient.HostConfig{
+		NetworkMode: "backend",
+		Links:       []string{"c1"},
+	}}}
+	_, err = f.Filter(config, nodes)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "--net=backend")
+}
@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/pkg/units"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+var (
+	// ErrNoDiskSpaceAvailable is exported
+	ErrNoDiskSpaceAvailable = errors.New("No node with enough disk space available in the cluster")
+
+	// diskFilter is the instance returned by New, configured by
+	// SetDiskThreshold.
+	diskFilter = &DiskFilter{MaxUsage: 0.95}
+)
+
+// DiskFilter doesn't schedule containers on nodes whose storage is almost
+// full. Nodes whose storage driver doesn't report its space are accepted.
+type DiskFilter struct {
+	// MaxUsage is the fraction of the data space above which a node is
+	// rejected, 0 to disable.
+	MaxUsage float64
+	// MinFree is the data space, in bytes, below which a node is rejected,
+	// 0 to disable.
+	MinFree int64
+}
+
+// SetDiskThreshold configures the disk filter from a threshold which is
+// either a maximum usage (ex. 90%) or a minimum free space (ex. 10g).
+func SetDiskThreshold(value string) error {
+	if strings.HasSuffix(value, "%") {
+		usage, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || usage < 0 || usage > 100 {
+			return fmt.Errorf("invalid disk threshold %q", value)
+		}
+		diskFilter.MaxUsage, diskFilter.MinFree = usage/100, 0
+		return nil
+	}
+
+	free, err := units.RAMInBytes(value)
+	if err != nil || free < 0 {
+		return fmt.Errorf("invalid disk threshold %q", value)
+	}
+	diskFilter.MaxUsage, diskFilter.MinFree = 0, free
+	return nil
+}
+
+// Name returns the name of the filter
+func (f *DiskFilter) Name() string {
+	return "disk"
+}
+
+// Filter is exported
+func (f *DiskFilter) Filter(_ *cluster.ContainerConfig, nodes []*node.Node) ([]*node.Node, error) {
+	result := []*node.Node{}
+	for _, node := range nodes {
+		if f.accept(node) {
+			result = append(result, node)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNoDiskSpaceAvailable
+	}
+
+	return result, nil
+}
+
+func (f *DiskFilter) accept(node *node.Node) bool {
+	if node.TotalDisk <= 0 {
+		return true
+	}
+	if f.MaxUsage > 0 && float64(node.UsedDisk) > f.MaxUsage*float64(node.TotalDisk) {
+		return false
+	}
+	if f.MinFree > 0 && node.TotalDisk-node.UsedDisk < f.MinFree {
+		return false
+	}
+	return true
+}
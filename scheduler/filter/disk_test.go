@@ -0,0 +1,47 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskFilter(t *testing.T) {
+	var (
+		f     = DiskFilter{MaxUsage: 0.9}
+		nodes = []*node.Node{
+			{ID: "node-0-id", Name: "node-0-name"},
+			{ID: "node-1-id", Name: "node-1-name", UsedDisk: 50, TotalDisk: 100},
+			{ID: "node-2-id", Name: "node-2-name", UsedDisk: 95, TotalDisk: 100},
+		}
+	)
+
+	// Nodes which don't report their disk space are accepted.
+	result, err := f.Filter(&cluster.ContainerConfig{}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, nodes[:2], result)
+
+	f = DiskFilter{MinFree: 60}
+	result, err = f.Filter(&cluster.ContainerConfig{}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, nodes[:1], result)
+
+	result, err = f.Filter(&cluster.ContainerConfig{}, nodes[1:])
+	assert.Equal(t, ErrNoDiskSpaceAvailable, err)
+	assert.Nil(t, result)
+}
+
+func TestSetDiskThreshold(t *testing.T) {
+	defer func(f DiskFilter) { *diskFilter = f }(*diskFilter)
+
+	assert.NoError(t, SetDiskThreshold("80%"))
+	assert.Equal(t, DiskFilter{MaxUsage: 0.8}, *diskFilter)
+
+	assert.NoError(t, SetDiskThreshold("10g"))
+	assert.Equal(t, DiskFilter{MinFree: 10 * 1024 * 1024 * 1024}, *diskFilter)
+
+	assert.Error(t, SetDiskThreshold("120%"))
+	assert.Error(t, SetDiskThreshold("lots"))
+}
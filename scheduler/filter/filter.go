@@ -5,6 +5,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/metrics"
 	"github.com/docker/swarm/scheduler/node"
 )
 
@@ -20,15 +21,23 @@ var (
 	filters []Filter
 	// ErrNotSupported is exported
 	ErrNotSupported = errors.New("filter not supported")
+
+	// Failures counts the attempts to select a node for a container which
+	// failed, by the filter (or strategy) rejecting the container.
+	Failures = metrics.NewCounterVec("swarm_scheduler_failures_total", "Number of failed attempts to select a node for a container, by filter or strategy rejecting it.", "filter")
 )
 
 func init() {
+	metrics.Register(Failures)
+
 	filters = []Filter{
 		&AffinityFilter{},
 		&HealthFilter{},
 		&ConstraintFilter{},
 		&PortFilter{},
 		&DependencyFilter{},
+		diskFilter,
+		&PlatformFilter{},
 	}
 }
 
@@ -53,13 +62,15 @@ func New(names []string) ([]Filter, error) {
 	return selectedFilters, nil
 }
 
-// ApplyFilters applies a set of filters in batch.
+// ApplyFilters applies a set of filters in batch. The filter rejecting the
+// container is counted in Failures.
 func ApplyFilters(filters []Filter, config *cluster.ContainerConfig, nodes []*node.Node) ([]*node.Node, error) {
 	var err error
 
 	for _, filter := range filters {
 		nodes, err = filter.Filter(config, nodes)
 		if err != nil {
+			Failures.Inc(filter.Name())
 			return nil, err
 		}
 	}
@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+)
+
+// PlatformFilter only schedules containers on nodes whose operating system
+// and architecture match the ones of their image.
+type PlatformFilter struct {
+}
+
+// Name returns the name of the filter
+func (f *PlatformFilter) Name() string {
+	return "platform"
+}
+
+// Filter is exported
+func (f *PlatformFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node) ([]*node.Node, error) {
+	platform, ok := imagePlatform(config.Image, nodes)
+	if !ok {
+		// The image isn't on any node yet: it will be pulled, for the
+		// platform of the node.
+		return nodes, nil
+	}
+
+	result := []*node.Node{}
+	for _, node := range nodes {
+		if platform.Compatible(cluster.Platform{OS: node.Labels["ostype"], Architecture: node.Labels["architecture"]}) {
+			result = append(result, node)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("No node compatible with the %s platform of image %s available in the cluster", platform, config.Image)
+	}
+
+	return result, nil
+}
+
+// imagePlatform returns the platform of the image from the first node that
+// has it.
+func imagePlatform(name string, nodes []*node.Node) (cluster.Platform, bool) {
+	if name == "" {
+		return cluster.Platform{}, false
+	}
+	for _, node := range nodes {
+		for _, image := range node.Images {
+			if !image.Match(name, true) {
+				continue
+			}
+			platform, err := image.Platform()
+			if err != nil {
+				log.WithFields(log.Fields{"name": node.Name, "image": name}).Debugf("Unable to inspect image: %v", err)
+				continue
+			}
+			if platform.OS == "" && platform.Architecture == "" {
+				continue
+			}
+			return platform, true
+		}
+	}
+	return cluster.Platform{}, false
+}
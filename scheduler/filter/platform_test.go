@@ -0,0 +1,50 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/node"
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPlatformFilter(t *testing.T) {
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(&dockerclient.Info{ID: "id", Name: "name", NCPU: 1, MemTotal: 1024}, nil)
+	client.On("Version").Return(&dockerclient.Version{Version: "1.9.0", Os: "linux", Arch: "arm"}, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
+	client.On("ListImages", mock.Anything).Return([]*dockerclient.Image{{Id: "image", RepoTags: []string{"armhf/busybox:latest"}}}, nil)
+	client.On("ListVolumes", mock.Anything).Return([]*dockerclient.Volume{}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	client.On("InspectImage", "image").Return(&dockerclient.ImageInfo{Id: "image", Os: "linux", Architecture: "arm"}, nil)
+	engine := cluster.NewEngine("test", 0)
+	assert.NoError(t, engine.ConnectWithClient(client))
+
+	var (
+		f     = PlatformFilter{}
+		nodes = []*node.Node{
+			{ID: "node-0-id", Name: "node-0-name", Labels: map[string]string{"ostype": "linux", "architecture": "amd64"}},
+			{ID: "node-1-id", Name: "node-1-name", Labels: map[string]string{"ostype": "linux", "architecture": "arm"}, Images: engine.Images(true)},
+			{ID: "node-2-id", Name: "node-2-name", Labels: map[string]string{}},
+		}
+	)
+
+	// Images which aren't on any node aren't filtered.
+	result, err := f.Filter(&cluster.ContainerConfig{dockerclient.ContainerConfig{Image: "busybox"}}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, nodes, result)
+
+	// Nodes of an unknown platform are accepted.
+	result, err = f.Filter(&cluster.ContainerConfig{dockerclient.ContainerConfig{Image: "armhf/busybox"}}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, nodes[1:], result)
+
+	// No node can run the image.
+	nodes = []*node.Node{{ID: "node-0-id", Name: "node-0-name", Labels: map[string]string{"ostype": "linux", "architecture": "amd64"}, Images: engine.Images(true)}}
+	result, err = f.Filter(&cluster.ContainerConfig{dockerclient.ContainerConfig{Image: "armhf/busybox"}}, nodes)
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
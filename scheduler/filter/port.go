@@ -21,6 +21,15 @@ func (p *PortFilter) Name() string {
 
 // Filter is exported
 func (p *PortFilter) Filter(config *cluster.ContainerConfig, nodes []*node.Node) ([]*node.Node, error) {
+	// Containers joining a local network can only run on the nodes which
+	// have it: their ports are only checked there, so that a port in use is
+	// reported as such rather than as a missing dependency.
+	if network := userNetwork(config); network != "" {
+		if withNetwork, ok := localNetworkNodes(network, nodes); ok {
+			nodes = withNetwork
+		}
+	}
+
 	if config.HostConfig.NetworkMode == "host" {
 		return p.filterHost(config, nodes)
 	}
@@ -120,5 +129,5 @@ func (p *PortFilter) compare(requested dockerclient.PortBinding, bindings map[st
 }
 
 func bindsAllInterfaces(binding dockerclient.PortBinding) bool {
-	return binding.HostIp == "0.0.0.0" || binding.HostIp == ""
+	return binding.HostIp == "0.0.0.0" || binding.HostIp == "::" || binding.HostIp == ""
 }
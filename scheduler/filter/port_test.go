@@ -185,6 +185,15 @@ func TestPortFilterDifferentInterfaces(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotContains(t, result, nodes[1])
 
+	// Request port 4242 on every IPv6 interface.
+	config = &cluster.ContainerConfig{dockerclient.ContainerConfig{HostConfig: dockerclient.HostConfig{
+		PortBindings: makeBinding("::", "4242"),
+	}}}
+	// nodes[1] should still be excluded since the port is not available on the same interface.
+	result, err = p.Filter(config, nodes)
+	assert.NoError(t, err)
+	assert.NotContains(t, result, nodes[1])
+
 	// Finally, request port 4242 on a different interface.
 	config = &cluster.ContainerConfig{dockerclient.ContainerConfig{HostConfig: dockerclient.HostConfig{
 		PortBindings: makeBinding("192.168.1.1", "4242"),
@@ -328,3 +337,38 @@ func TestPortFilterForHostMode(t *testing.T) {
 	assert.Equal(t, 2, len(result))
 	assert.NotContains(t, result, nodes[0])
 }
+
+func TestPortFilterLocalNetwork(t *testing.T) {
+	var (
+		p     = PortFilter{}
+		node0 = &cluster.Engine{ID: "node-0-id", Name: "node-0-name"}
+		nodes = []*node.Node{
+			{
+				ID:       "node-0-id",
+				Name:     "node-0-name",
+				Addr:     "node-0",
+				Networks: cluster.Networks{{ID: "local", Name: "backend", Scope: "local", Engine: node0}},
+			},
+			{
+				ID:   "node-1-id",
+				Name: "node-1-name",
+				Addr: "node-1",
+			},
+		}
+	)
+
+	config := &cluster.ContainerConfig{ContainerConfig: dockerclient.ContainerConfig{HostConfig: dockerclient.HostConfig{
+		NetworkMode:  "backend",
+		PortBindings: makeBinding("", "80"),
+	}}}
+	result, err := p.Filter(config, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes[:1])
+
+	// The port is only looked for on the nodes which have the network.
+	container := &cluster.Container{Container: dockerclient.Container{Id: "c1"}}
+	container.Info.HostConfig = &dockerclient.HostConfig{PortBindings: makeBinding("", "80")}
+	assert.NoError(t, nodes[0].AddContainer(container))
+	_, err = p.Filter(config, nodes)
+	assert.Error(t, err)
+}
@@ -16,11 +16,19 @@ type Node struct {
 	Labels     map[string]string
 	Containers []*cluster.Container
 	Images     []*cluster.Image
+	Networks   cluster.Networks
 
-	UsedMemory  int64
-	UsedCpus    int64
-	TotalMemory int64
-	TotalCpus   int64
+	// Total resources are the ones available to containers, once the
+	// resources reserved for the system are subtracted. CPUs are in
+	// thousandths of a CPU.
+	UsedMemory     int64
+	UsedMilliCpus  int64
+	TotalMemory    int64
+	TotalMilliCpus int64
+
+	// Data space of the storage of the node, 0 if unknown.
+	UsedDisk  int64
+	TotalDisk int64
 
 	IsHealthy bool
 }
@@ -28,18 +36,21 @@ type Node struct {
 // NewNode creates a node from an engine.
 func NewNode(e *cluster.Engine) *Node {
 	return &Node{
-		ID:          e.ID,
-		IP:          e.IP,
-		Addr:        e.Addr,
-		Name:        e.Name,
-		Labels:      e.Labels,
-		Containers:  e.Containers(),
-		Images:      e.Images(true),
-		UsedMemory:  e.UsedMemory(),
-		UsedCpus:    e.UsedCpus(),
-		TotalMemory: e.TotalMemory(),
-		TotalCpus:   e.TotalCpus(),
-		IsHealthy:   e.IsHealthy(),
+		ID:             e.ID,
+		IP:             e.IP,
+		Addr:           e.Addr,
+		Name:           e.Name,
+		Labels:         e.Labels,
+		Containers:     e.Containers(),
+		Images:         e.Images(true),
+		Networks:       e.Networks(),
+		UsedMemory:     e.UsedMemory(),
+		UsedMilliCpus:  e.UsedMilliCpus(),
+		TotalMemory:    e.AllocatableMemory(),
+		TotalMilliCpus: e.AllocatableMilliCpus(),
+		UsedDisk:       e.DiskUsed,
+		TotalDisk:      e.DiskTotal,
+		IsHealthy:      e.IsHealthy(),
 	}
 }
 
@@ -70,13 +81,13 @@ func (n *Node) Container(IDOrName string) *cluster.Container {
 // AddContainer injects a container into the internal state.
 func (n *Node) AddContainer(container *cluster.Container) error {
 	if container.Config != nil {
-		memory := container.Config.Memory
-		cpus := container.Config.CpuShares
-		if n.TotalMemory-memory < 0 || n.TotalCpus-cpus < 0 {
+		memory := container.Config.ReservedMemory()
+		milliCpus := container.Config.ReservedMilliCpus()
+		if n.TotalMemory-memory < 0 || n.TotalMilliCpus-milliCpus < 0 {
 			return errors.New("not enough resources")
 		}
 		n.UsedMemory = n.UsedMemory + memory
-		n.UsedCpus = n.UsedCpus + cpus
+		n.UsedMilliCpus = n.UsedMilliCpus + milliCpus
 	}
 	n.Containers = append(n.Containers, container)
 	return nil
@@ -3,13 +3,25 @@ package scheduler
 import (
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/metrics"
 	"github.com/docker/swarm/scheduler/filter"
 	"github.com/docker/swarm/scheduler/node"
 	"github.com/docker/swarm/scheduler/strategy"
 )
 
+var (
+	schedulingAttempts = metrics.NewCounterVec("swarm_scheduler_attempts_total", "Number of attempts to select a node for a container.")
+	schedulingLatency  = metrics.NewHistogramVec("swarm_scheduler_latency_seconds", "Time spent selecting a node for a container.", metrics.DefaultBuckets)
+)
+
+func init() {
+	metrics.Register(schedulingAttempts)
+	metrics.Register(schedulingLatency)
+}
+
 // Scheduler is exported
 type Scheduler struct {
 	sync.Mutex
@@ -28,12 +40,22 @@ func New(strategy strategy.PlacementStrategy, filters []filter.Filter) *Schedule
 
 // SelectNodeForContainer will find a nice home for our container.
 func (s *Scheduler) SelectNodeForContainer(nodes []*node.Node, config *cluster.ContainerConfig) (*node.Node, error) {
+	start := time.Now()
+	defer func() {
+		schedulingLatency.Observe(time.Since(start).Seconds())
+	}()
+	schedulingAttempts.Inc()
+
 	accepted, err := filter.ApplyFilters(s.filters, config, nodes)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.strategy.PlaceContainer(config, accepted)
+	n, err := s.strategy.PlaceContainer(config, accepted)
+	if err != nil {
+		filter.Failures.Inc(s.strategy.Name())
+	}
+	return n, err
 }
 
 // Strategy returns the strategy name
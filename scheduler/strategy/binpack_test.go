@@ -14,11 +14,11 @@ func createNode(ID string, memory int64, cpus int64) *node.Node {
 	oc := 0.05
 	memory = int64(float64(memory) + float64(memory)*oc)
 	return &node.Node{
-		ID:          ID,
-		IP:          ID,
-		Addr:        ID,
-		TotalMemory: memory * 1024 * 1024 * 1024,
-		TotalCpus:   cpus,
+		ID:             ID,
+		IP:             ID,
+		Addr:           ID,
+		TotalMemory:    memory * 1024 * 1024 * 1024,
+		TotalMilliCpus: cpus * 1000,
 	}
 }
 
@@ -106,14 +106,14 @@ func TestPlaceContainerCPU(t *testing.T) {
 	node1, err := s.PlaceContainer(config, nodes)
 	assert.NoError(t, err)
 	assert.NoError(t, node1.AddContainer(createContainer("c1", config)))
-	assert.Equal(t, node1.UsedCpus, int64(1))
+	assert.Equal(t, node1.UsedMilliCpus, int64(1000))
 
 	// add another container 1CPU
 	config = createConfig(0, 1)
 	node2, err := s.PlaceContainer(config, nodes)
 	assert.NoError(t, err)
 	assert.NoError(t, node2.AddContainer(createContainer("c2", config)))
-	assert.Equal(t, node2.UsedCpus, int64(2))
+	assert.Equal(t, node2.UsedMilliCpus, int64(2000))
 
 	// check that both containers ended on the same node
 	assert.Equal(t, node1.ID, node2.ID)
@@ -151,6 +151,28 @@ func TestPlaceContainerHuge(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestPlaceContainerReservations(t *testing.T) {
+	s := &BinpackPlacementStrategy{}
+
+	nodes := []*node.Node{createNode("node-1", 4, 2)}
+
+	// containers limited to 2G and 1 CPU but reserving half of it
+	config := createConfig(2, 1)
+	config.Labels[cluster.SwarmLabelNamespace+".reserve.memory"] = "1g"
+	config.Labels[cluster.SwarmLabelNamespace+".reserve.cpu"] = "0.5"
+	for i := 0; i < 4; i++ {
+		node, err := s.PlaceContainer(config, nodes)
+		assert.NoError(t, err)
+		assert.NoError(t, node.AddContainer(createContainer(fmt.Sprintf("c%d", i), config)))
+	}
+	assert.Equal(t, nodes[0].UsedMemory, int64(4*1024*1024*1024))
+	assert.Equal(t, nodes[0].UsedMilliCpus, int64(2000))
+
+	// the node is fully reserved
+	_, err := s.PlaceContainer(config, nodes)
+	assert.Error(t, err)
+}
+
 func TestPlaceContainerOvercommit(t *testing.T) {
 	s, err := New("binpacking")
 	assert.NoError(t, err)
@@ -252,7 +274,7 @@ func TestPlaceContainerDemo(t *testing.T) {
 	// remove container in the middle
 	node2.Containers = nil
 	node2.UsedMemory = 0
-	node2.UsedCpus = 0
+	node2.UsedMilliCpus = 0
 
 	// add another container
 	config = createConfig(1, 0)
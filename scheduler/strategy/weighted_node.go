@@ -35,12 +35,15 @@ func (n weightedNodeList) Less(i, j int) bool {
 func weighNodes(config *cluster.ContainerConfig, nodes []*node.Node) (weightedNodeList, error) {
 	weightedNodes := weightedNodeList{}
 
+	// Burstable containers are placed according to their reservations, not
+	// their limits.
+	memory, milliCpus := config.ReservedMemory(), config.ReservedMilliCpus()
 	for _, node := range nodes {
 		nodeMemory := node.TotalMemory
-		nodeCpus := node.TotalCpus
+		nodeMilliCpus := node.TotalMilliCpus
 
 		// Skip nodes that are smaller than the requested resources.
-		if nodeMemory < int64(config.Memory) || nodeCpus < config.CpuShares {
+		if nodeMemory < memory || nodeMilliCpus < milliCpus {
 			continue
 		}
 
@@ -49,11 +52,11 @@ func weighNodes(config *cluster.ContainerConfig, nodes []*node.Node) (weightedNo
 			memoryScore int64 = 100
 		)
 
-		if config.CpuShares > 0 {
-			cpuScore = (node.UsedCpus + config.CpuShares) * 100 / nodeCpus
+		if milliCpus > 0 {
+			cpuScore = (node.UsedMilliCpus + milliCpus) * 100 / nodeMilliCpus
 		}
-		if config.Memory > 0 {
-			memoryScore = (node.UsedMemory + config.Memory) * 100 / nodeMemory
+		if memory > 0 {
+			memoryScore = (node.UsedMemory + memory) * 100 / nodeMemory
 		}
 
 		if cpuScore <= 100 && memoryScore <= 100 {